@@ -0,0 +1,280 @@
+/**
+ * ============================================================================
+ *                                  ヽ(°〇°)ﾉ
+ *                                THE BUILTINS
+ * ============================================================================
+ */
+
+package lang
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+/*
+The builtins are the functions every program has without declaring them. Both
+backends call them through the same registry, a program can not declare a
+function with the name of a builtin.
+
+  print(a, b, ...)   prints the values separated by blanks, gives back nil
+  len(v)             the number of characters of a string, items of a list
+                     or entries of a map
+  str(v)             the value the way print shows it
+  int(v)             an int from a number, a bool or a string of digits, a
+                     float is truncated
+  float(v)           a float from a number, a bool or a string of a number
+  abs(n)             the absolute value of a number
+  min(n, ...)        the smallest of the numbers, as it is
+  max(n, ...)        the biggest of the numbers, as it is
+  push(l, v, ...)    appends the values to the list, gives back nil
+  pop(l)             removes the last item of the list and gives it back
+  has(m, k)          whether the map has the key
+  delete(m, k)       removes the key from the map if it is there, gives back
+                     nil
+  keys(m)            a list of the keys of the map, in the order they were
+                     added
+*/
+
+// variadic is the maxParams of a builtin taking any number of params
+const variadic = -1
+
+// builtin is a function of the runtime. call gets the params already worked
+// out and checked against minParams and maxParams, t is the call for the
+// error messages.
+type builtin struct {
+	name      string
+	minParams int
+	maxParams int
+	call      func(out io.Writer, t Token, params []Value) (Value, *RuntimeError)
+}
+
+// builtins in the order of their vm index
+var builtins = []*builtin{
+	{"print", 0, variadic, builtinPrint},
+	{"len", 1, 1, builtinLen},
+	{"str", 1, 1, builtinStr},
+	{"int", 1, 1, builtinInt},
+	{"abs", 1, 1, builtinAbs},
+	{"min", 1, variadic, builtinMin},
+	{"max", 1, variadic, builtinMax},
+	{"float", 1, 1, builtinFloat},
+	{"push", 2, variadic, builtinPush},
+	{"pop", 1, 1, builtinPop},
+	{"has", 2, 2, builtinHas},
+	{"delete", 2, 2, builtinDelete},
+	{"keys", 1, 1, builtinKeys},
+}
+
+// builtinIndex map[name]index in builtins
+var builtinIndex = func() map[string]int {
+	index := make(map[string]int, len(builtins))
+	for i, b := range builtins {
+		index[b.name] = i
+	}
+	return index
+}()
+
+// lookupBuiltin gives back the builtin called name, or nil
+func lookupBuiltin(name string) *builtin {
+	if i, ok := builtinIndex[name]; ok {
+		return builtins[i]
+	}
+	return nil
+}
+
+// checkParams makes sure the builtin can be called with that many params
+func (b *builtin) checkParams(t Token, params int) *RuntimeError {
+	switch {
+	case b.minParams == b.maxParams && params != b.minParams:
+		return runtimeError(t, codeWrongArity, "%s wants %d params but got %d", b.name, b.minParams, params)
+	case params < b.minParams:
+		return runtimeError(t, codeWrongArity, "%s wants at least %d params but got %d", b.name, b.minParams, params)
+	case b.maxParams != variadic && params > b.maxParams:
+		return runtimeError(t, codeWrongArity, "%s wants at most %d params but got %d", b.name, b.maxParams, params)
+	}
+	return nil
+}
+
+func builtinPrint(out io.Writer, t Token, params []Value) (Value, *RuntimeError) {
+	texts := make([]string, len(params))
+	for i, v := range params {
+		texts[i] = v.String()
+	}
+	fmt.Fprintln(out, strings.Join(texts, " "))
+	return Value{}, nil
+}
+
+func builtinLen(out io.Writer, t Token, params []Value) (Value, *RuntimeError) {
+	switch params[0].kind {
+	case vString:
+		return IntValue(utf8.RuneCountInString(params[0].str)), nil
+	case vList:
+		return IntValue(len(params[0].list.items)), nil
+	case vMap:
+		return IntValue(len(params[0].dict.keys)), nil
+	}
+	return Value{}, typeError(t, "len wants a string, a list or a map but got %s", params[0].Type())
+}
+
+func builtinStr(out io.Writer, t Token, params []Value) (Value, *RuntimeError) {
+	return StringValue(params[0].String()), nil
+}
+
+func builtinInt(out io.Writer, t Token, params []Value) (Value, *RuntimeError) {
+	v := params[0]
+	switch v.kind {
+	case vInt, vBool:
+		return IntValue(v.num), nil
+	case vFloat:
+		// NaN, the infinities and the floats too big for an int fail both
+		// comparisons, -float64(math.MinInt) is the first float above MaxInt
+		if !(v.flt >= float64(math.MinInt) && v.flt < -float64(math.MinInt)) {
+			return Value{}, runtimeError(t, codeConversion, "can not convert %s to int", v)
+		}
+		return IntValue(int(v.flt)), nil
+	case vString:
+		i, err := strconv.Atoi(strings.TrimSpace(v.str))
+		if err != nil {
+			return Value{}, runtimeError(t, codeConversion, "can not convert %q to int", v.str)
+		}
+		return IntValue(i), nil
+	}
+	return Value{}, typeError(t, "int wants a number, a bool or a string but got %s", v.Type())
+}
+
+func builtinFloat(out io.Writer, t Token, params []Value) (Value, *RuntimeError) {
+	v := params[0]
+	switch v.kind {
+	case vInt, vBool:
+		return FloatValue(float64(v.num)), nil
+	case vFloat:
+		return v, nil
+	case vString:
+		f, err := strconv.ParseFloat(strings.TrimSpace(v.str), 64)
+		if err != nil {
+			return Value{}, runtimeError(t, codeConversion, "can not convert %q to float", v.str)
+		}
+		return FloatValue(f), nil
+	}
+	return Value{}, typeError(t, "float wants a number, a bool or a string but got %s", v.Type())
+}
+
+// numbers makes sure all the params of the builtin called name are numbers
+func numbers(t Token, name string, params []Value) *RuntimeError {
+	for _, v := range params {
+		if !v.isNumber() {
+			return typeError(t, "%s wants numbers but got %s", name, v.Type())
+		}
+	}
+	return nil
+}
+
+func builtinAbs(out io.Writer, t Token, params []Value) (Value, *RuntimeError) {
+	if err := numbers(t, "abs", params); err != nil {
+		return Value{}, err
+	}
+	if params[0].kind == vFloat {
+		return FloatValue(math.Abs(params[0].flt)), nil
+	}
+	if params[0].num < 0 {
+		return IntValue(-params[0].num), nil
+	}
+	return params[0], nil
+}
+
+// lessNumber compares two numbers, the ints are not promoted when both are
+// ints so that big ints stay exact
+func lessNumber(a Value, b Value) bool {
+	if a.kind == vInt && b.kind == vInt {
+		return a.num < b.num
+	}
+	return a.float() < b.float()
+}
+
+func builtinMin(out io.Writer, t Token, params []Value) (Value, *RuntimeError) {
+	if err := numbers(t, "min", params); err != nil {
+		return Value{}, err
+	}
+	result := params[0]
+	for _, v := range params[1:] {
+		if lessNumber(v, result) {
+			result = v
+		}
+	}
+	return result, nil
+}
+
+func builtinMax(out io.Writer, t Token, params []Value) (Value, *RuntimeError) {
+	if err := numbers(t, "max", params); err != nil {
+		return Value{}, err
+	}
+	result := params[0]
+	for _, v := range params[1:] {
+		if lessNumber(result, v) {
+			result = v
+		}
+	}
+	return result, nil
+}
+
+func builtinPush(out io.Writer, t Token, params []Value) (Value, *RuntimeError) {
+	l := params[0]
+	if l.kind != vList {
+		return Value{}, typeError(t, "push wants a list but got %s", l.Type())
+	}
+	l.list.items = append(l.list.items, params[1:]...)
+	return Value{}, nil
+}
+
+func builtinPop(out io.Writer, t Token, params []Value) (Value, *RuntimeError) {
+	l := params[0]
+	if l.kind != vList {
+		return Value{}, typeError(t, "pop wants a list but got %s", l.Type())
+	}
+	last := len(l.list.items) - 1
+	if last < 0 {
+		return Value{}, runtimeError(t, codeIndexOutOfRange, "pop from an empty list")
+	}
+	v := l.list.items[last]
+	l.list.items = l.list.items[:last]
+	return v, nil
+}
+
+// mapParams makes sure the builtin called name got a map and a valid key
+func mapParams(t Token, name string, params []Value) (*dict, Value, *RuntimeError) {
+	if params[0].kind != vMap {
+		return nil, Value{}, typeError(t, "%s wants a map but got %s", name, params[0].Type())
+	}
+	key, err := mapKey(t, params[1])
+	return params[0].dict, key, err
+}
+
+func builtinHas(out io.Writer, t Token, params []Value) (Value, *RuntimeError) {
+	d, key, err := mapParams(t, "has", params)
+	if err != nil {
+		return Value{}, err
+	}
+	_, ok := d.values[key]
+	return BoolValue(ok), nil
+}
+
+func builtinDelete(out io.Writer, t Token, params []Value) (Value, *RuntimeError) {
+	d, key, err := mapParams(t, "delete", params)
+	if err != nil {
+		return Value{}, err
+	}
+	d.remove(key)
+	return Value{}, nil
+}
+
+func builtinKeys(out io.Writer, t Token, params []Value) (Value, *RuntimeError) {
+	if params[0].kind != vMap {
+		return Value{}, typeError(t, "keys wants a map but got %s", params[0].Type())
+	}
+	return ListValue(params[0].dict.keys...), nil
+}
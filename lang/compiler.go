@@ -0,0 +1,591 @@
+/**
+ * ============================================================================
+ *                                  ᕦ(ò_óˇ)ᕤ
+ *                                THE COMPILER
+ * ============================================================================
+ */
+
+package lang
+
+import (
+	"fmt"
+)
+
+/*
+The compiler turns the ast into bytecode for the vm. Every function, and the
+program itself, becomes a chunk: a flat slice of ints where an opcode is
+followed by its operands.
+
+  a = 1 + 2
+  print(a)
+
+becomes
+
+  0  opConst 0          push constants[0] = 1
+  2  opConst 1          push constants[1] = 2
+  4  opBinary 0         pop 2 values, push them through tokens[0] = `+`
+  6  opDup              the value of `a = ...` is the assigned value
+  7  opStoreGlobal 0    pop into globals[0] = a
+  9  opPop              the statement does not use the value
+  10 opLoadGlobal 0 1   push globals[0], tokens[1] = `a` is for the error if it is unset
+  13 opBuiltin 0 1 2    call builtins[0] = print with 1 param, push its result
+  17 opPop
+  18 opReturn           the end of the program
+
+Variables are slots instead of names: the globals have one slot each in the
+program, the params and the variables assigned in a function get a slot in
+the frame of the call.
+*/
+
+// opcodes of the vm, the comments tell the operands
+const (
+	opConst       = iota // constant index
+	opPop                //
+	opDup                //
+	opDup2               // pushes the two values on top again
+	opLoadGlobal         // global slot, token index
+	opStoreGlobal        // global slot
+	opLoadLocal          // local slot, global slot to read while the local is unset, token index
+	opStoreLocal         // local slot
+	opBinary             // token index of the operator
+	opUnary              // token index of the operator
+	opJump               // target
+	opJumpIfFalse        // target
+	opJumpIfTrue         // target
+	opDefine             // chunk index, function slot
+	opCall               // function slot, number of params, token index
+	opBuiltin            // builtin index, number of params, token index
+	opList               // number of items
+	opMap                // number of keys and values, token index of the `{`
+	opIndex              // token index of the index
+	opSetIndex           // token index of the index
+	opReturn             //
+)
+
+// chunk is the bytecode of a function or of the program
+type chunk struct {
+	name string
+	code []int
+	// params take the first local slots
+	params int
+	locals int
+	// tokens of the operators and calls, for the error messages
+	tokens []Token
+}
+
+// Program is everything the vm needs to run
+type Program struct {
+	constants []Value
+	// names of the global and function slots
+	globals   []string
+	functions []string
+	// chunks[0] is the program itself
+	chunks []*chunk
+}
+
+type compiler struct {
+	program *Program
+	chunk   *chunk
+	// locals map[identifier]slot of the function being compiled, nil at the
+	// top level
+	locals        map[string]int
+	globalSlots   map[string]int
+	functionSlots map[string]int
+	// loops being compiled, the innermost is the last one
+	loops []*loop
+}
+
+// loop keeps the jumps of the `break` and `continue` inside a loop, they are
+// patched once the loop is compiled and we know where they go. A switch is a
+// loop that only takes the `break` without a label.
+type loop struct {
+	label     string
+	isSwitch  bool
+	breaks    []int
+	continues []int
+}
+
+// Compile the ast of a program into bytecode
+func Compile(ast *Node) (*Program, error) {
+	c := &compiler{
+		program:       &Program{},
+		chunk:         &chunk{name: "<program>"},
+		globalSlots:   make(map[string]int),
+		functionSlots: make(map[string]int),
+	}
+	c.program.chunks = append(c.program.chunks, c.chunk)
+	if err := c.statement(ast); err != nil {
+		return nil, err
+	}
+	c.emit(opReturn)
+	return c.program, nil
+}
+
+func (c *compiler) emit(code ...int) int {
+	c.chunk.code = append(c.chunk.code, code...)
+	return len(c.chunk.code) - len(code)
+}
+
+// emitJump emits a jump whose target is set later by patch
+func (c *compiler) emitJump(op int) int {
+	return c.emit(op, -1)
+}
+
+// patch the target of the jump at pos to the next instruction
+func (c *compiler) patch(pos int) {
+	c.chunk.code[pos+1] = len(c.chunk.code)
+}
+
+func (c *compiler) constant(v Value) int {
+	c.program.constants = append(c.program.constants, v)
+	return len(c.program.constants) - 1
+}
+
+// token keeps t for the error messages of the instruction
+func (c *compiler) token(t Token) int {
+	c.chunk.tokens = append(c.chunk.tokens, t)
+	return len(c.chunk.tokens) - 1
+}
+
+func (c *compiler) globalSlot(name string) int {
+	if slot, ok := c.globalSlots[name]; ok {
+		return slot
+	}
+	c.globalSlots[name] = len(c.program.globals)
+	c.program.globals = append(c.program.globals, name)
+	return c.globalSlots[name]
+}
+
+func (c *compiler) functionSlot(name string) int {
+	if slot, ok := c.functionSlots[name]; ok {
+		return slot
+	}
+	c.functionSlots[name] = len(c.program.functions)
+	c.program.functions = append(c.program.functions, name)
+	return c.functionSlots[name]
+}
+
+func (c *compiler) statement(n *Node) error {
+	switch n.Kind {
+	case aProgram, aStatement:
+		for i := range n.Body {
+			if err := c.statement(&n.Body[i]); err != nil {
+				return err
+			}
+		}
+
+	case aStatementIf:
+		if err := c.expression(&n.Params[0]); err != nil {
+			return err
+		}
+		ifFalse := c.emitJump(opJumpIfFalse)
+		if err := c.statement(&n.Body[0]); err != nil {
+			return err
+		}
+		if len(n.Body) > 1 {
+			end := c.emitJump(opJump)
+			c.patch(ifFalse)
+			if err := c.statement(&n.Body[1]); err != nil {
+				return err
+			}
+			c.patch(end)
+		} else {
+			c.patch(ifFalse)
+		}
+
+	case aStatementWhile:
+		start := len(c.chunk.code)
+		if err := c.expression(&n.Params[0]); err != nil {
+			return err
+		}
+		end := c.emitJump(opJumpIfFalse)
+		l, err := c.loopBody(n)
+		if err != nil {
+			return err
+		}
+		c.patchAll(l.continues)
+		c.emit(opJump, start)
+		c.patch(end)
+		c.patchAll(l.breaks)
+
+	case aStatementFor:
+		// for (Params[0]; Params[2]; Params[4]) Body[0]
+		if err := c.statement(&n.Params[0]); err != nil {
+			return err
+		}
+		start := len(c.chunk.code)
+		end := -1
+		if n.Params[2].Kind != aBlank {
+			if err := c.expression(&n.Params[2]); err != nil {
+				return err
+			}
+			end = c.emitJump(opJumpIfFalse)
+		}
+		l, err := c.loopBody(n)
+		if err != nil {
+			return err
+		}
+		// `continue` goes on with the step
+		c.patchAll(l.continues)
+		if err := c.statement(&n.Params[4]); err != nil {
+			return err
+		}
+		c.emit(opJump, start)
+		if end >= 0 {
+			c.patch(end)
+		}
+		c.patchAll(l.breaks)
+
+	case aStatementSwitch:
+		return c.switchStatement(n)
+
+	case aStatementBreak, aStatementContinue:
+		l := c.loop(n)
+		if l == nil {
+			return fmt.Errorf("%s outside of a loop at line %d, col %d", n.Name, n.token.line, n.token.col)
+		}
+		jump := c.emitJump(opJump)
+		if n.Kind == aStatementBreak {
+			l.breaks = append(l.breaks, jump)
+		} else {
+			l.continues = append(l.continues, jump)
+		}
+
+	case aStatementReturn:
+		if len(n.Params) > 0 {
+			if err := c.expression(&n.Params[0]); err != nil {
+				return err
+			}
+		} else {
+			c.emit(opConst, c.constant(Value{}))
+		}
+		c.emit(opReturn)
+
+	case aFunction:
+		index, err := c.function(n)
+		if err != nil {
+			return err
+		}
+		c.emit(opDefine, index, c.functionSlot(n.Name))
+
+	case aBlank:
+
+	default:
+		// an expression whose value nobody uses
+		if err := c.expression(n); err != nil {
+			return err
+		}
+		c.emit(opPop)
+	}
+	return nil
+}
+
+// switchStatement compiles a switch. The subject stays on the stack while it
+// is compared with the values of the cases, every case pops it before its
+// statements run:
+//
+//	subject
+//	dup value0 == jumpIfTrue case0 ...   for every value of every case
+//	jump default                         or pop and jump end without one
+//	case0: pop statements jump end
+//	...
+func (c *compiler) switchStatement(n *Node) error {
+	if err := c.expression(&n.Params[0]); err != nil {
+		return err
+	}
+	matches := make([][]int, len(n.Body))
+	defaultCase := -1
+	for i := range n.Body {
+		if n.Body[i].token.kind == tDefault {
+			defaultCase = i
+		}
+		for j := range n.Body[i].Params {
+			value := &n.Body[i].Params[j]
+			c.emit(opDup)
+			if err := c.expression(value); err != nil {
+				return err
+			}
+			c.emit(opBinary, c.token(equalsToken(value.token)))
+			matches[i] = append(matches[i], c.emitJump(opJumpIfTrue))
+		}
+	}
+	noMatch := c.emitJump(opJump)
+	// a `break` without a label jumps to the end as well
+	l := &loop{isSwitch: true}
+	c.loops = append(c.loops, l)
+	defer func() {
+		c.loops = c.loops[:len(c.loops)-1]
+	}()
+	var ends []int
+	for i := range n.Body {
+		c.patchAll(matches[i])
+		if i == defaultCase {
+			c.patch(noMatch)
+		}
+		c.emit(opPop)
+		for j := range n.Body[i].Body {
+			if err := c.statement(&n.Body[i].Body[j]); err != nil {
+				return err
+			}
+		}
+		ends = append(ends, c.emitJump(opJump))
+	}
+	if defaultCase < 0 {
+		c.patch(noMatch)
+		c.emit(opPop)
+	}
+	c.patchAll(ends)
+	c.patchAll(l.breaks)
+	return nil
+}
+
+// loopBody compiles the body of the loop n, and gives back its `break` and
+// `continue` jumps to patch
+func (c *compiler) loopBody(n *Node) (*loop, error) {
+	l := &loop{label: n.Value}
+	c.loops = append(c.loops, l)
+	defer func() {
+		c.loops = c.loops[:len(c.loops)-1]
+	}()
+	return l, c.statement(&n.Body[0])
+}
+
+// loop finds the loop the `break` or `continue` n jumps out of, the
+// innermost loop or switch if n is a `break` without a label
+func (c *compiler) loop(n *Node) *loop {
+	for i := len(c.loops) - 1; i >= 0; i-- {
+		l := c.loops[i]
+		if l.isSwitch && (n.Value != "" || n.Kind == aStatementContinue) {
+			continue
+		}
+		if n.Value == "" || l.label == n.Value {
+			return l
+		}
+	}
+	return nil
+}
+
+// patchAll patches the jumps to the next instruction
+func (c *compiler) patchAll(jumps []int) {
+	for _, pos := range jumps {
+		c.patch(pos)
+	}
+}
+
+// function compiles the body of a function into a chunk of its own and
+// gives back the index of the chunk
+func (c *compiler) function(n *Node) (int, error) {
+	outerChunk, outerLocals, outerLoops := c.chunk, c.locals, c.loops
+	defer func() {
+		c.chunk, c.locals, c.loops = outerChunk, outerLocals, outerLoops
+	}()
+	c.loops = nil
+
+	c.chunk = &chunk{name: n.Name, params: len(n.Params)}
+	c.locals = make(map[string]int)
+	for _, param := range n.Params {
+		c.locals[param.Name] = len(c.locals)
+	}
+	// everything assigned inside the function is a local
+	collectLocals(&n.Body[0], c.locals)
+	c.chunk.locals = len(c.locals)
+
+	c.program.chunks = append(c.program.chunks, c.chunk)
+	index := len(c.program.chunks) - 1
+	if err := c.statement(&n.Body[0]); err != nil {
+		return 0, err
+	}
+	// falling off the end gives back nothing
+	c.emit(opConst, c.constant(Value{}), opReturn)
+	return index, nil
+}
+
+// collectLocals gives a slot to every variable assigned in n, the functions
+// declared inside have their own locals
+func collectLocals(n *Node, locals map[string]int) {
+	if n.Kind == aFunction {
+		return
+	}
+	if n.Kind == aAssignmentStatement && n.Params[0].Kind != aIndexExpression {
+		if _, ok := locals[n.Params[0].Name]; !ok {
+			locals[n.Params[0].Name] = len(locals)
+		}
+	}
+	for i := range n.Params {
+		collectLocals(&n.Params[i], locals)
+	}
+	for i := range n.Body {
+		collectLocals(&n.Body[i], locals)
+	}
+}
+
+func (c *compiler) expression(n *Node) error {
+	switch n.Kind {
+	case aNumberLiteral, aFloatLiteral, aStringLiteral, aBoolLiteral, aNilLiteral:
+		v, err := literalValue(n)
+		if err != nil {
+			return err
+		}
+		c.emit(opConst, c.constant(v))
+		return nil
+
+	case aListLiteral:
+		for i := range n.Params {
+			if err := c.expression(&n.Params[i]); err != nil {
+				return err
+			}
+		}
+		c.emit(opList, len(n.Params))
+		return nil
+
+	case aMapLiteral:
+		for i := range n.Params {
+			if err := c.expression(&n.Params[i]); err != nil {
+				return err
+			}
+		}
+		c.emit(opMap, len(n.Params), c.token(n.token))
+		return nil
+
+	case aIndexExpression:
+		if err := c.expression(&n.Params[0]); err != nil {
+			return err
+		}
+		if err := c.expression(&n.Params[1]); err != nil {
+			return err
+		}
+		c.emit(opIndex, c.token(n.token))
+		return nil
+
+	case aAssignmentStatement:
+		if target := &n.Params[0]; target.Kind == aIndexExpression {
+			// the list, the index and the value, opSetIndex leaves the value
+			for _, part := range []*Node{&target.Params[0], &target.Params[1], &n.Params[1]} {
+				if err := c.expression(part); err != nil {
+					return err
+				}
+			}
+			c.emit(opSetIndex, c.token(target.token))
+			return nil
+		}
+		if err := c.expression(&n.Params[1]); err != nil {
+			return err
+		}
+		c.emit(opDup)
+		name := n.Params[0].Name
+		if slot, ok := c.locals[name]; ok {
+			c.emit(opStoreLocal, slot)
+		} else {
+			c.emit(opStoreGlobal, c.globalSlot(name))
+		}
+		return nil
+
+	case aCompoundIndexAssignment:
+		// the list and the index stay for opSetIndex, a copy of them is read
+		target := &n.Params[0]
+		if err := c.expression(&target.Params[0]); err != nil {
+			return err
+		}
+		if err := c.expression(&target.Params[1]); err != nil {
+			return err
+		}
+		index := c.token(target.token)
+		c.emit(opDup2, opIndex, index)
+		if err := c.expression(&n.Params[1]); err != nil {
+			return err
+		}
+		c.emit(opBinary, c.token(n.token), opSetIndex, index)
+		return nil
+
+	case aExpression:
+	default:
+		return fmt.Errorf("can not compile node %d at line %d, col %d", n.Kind, n.token.line, n.token.col)
+	}
+
+	if prefixOperators[n.token.kind] && len(n.Params) == 1 {
+		if err := c.expression(&n.Params[0]); err != nil {
+			return err
+		}
+		c.emit(opUnary, c.token(n.token))
+		return nil
+	}
+	if (n.token.kind == tAnd || n.token.kind == tOr) && len(n.Params) == 2 {
+		return c.logical(n)
+	}
+	if _, ok := infixOperators[n.token.kind]; ok && len(n.Params) == 2 {
+		if err := c.expression(&n.Params[0]); err != nil {
+			return err
+		}
+		if err := c.expression(&n.Params[1]); err != nil {
+			return err
+		}
+		c.emit(opBinary, c.token(n.token))
+		return nil
+	}
+
+	switch n.Name {
+	case "(":
+		return c.expression(&n.Params[0])
+
+	}
+
+	// the parser gives calls non-nil params, even without arguments
+	if n.Params != nil {
+		for i := range n.Params {
+			if err := c.expression(&n.Params[i]); err != nil {
+				return err
+			}
+		}
+		if index, ok := builtinIndex[n.Name]; ok {
+			c.emit(opBuiltin, index, len(n.Params), c.token(n.token))
+			return nil
+		}
+		c.emit(opCall, c.functionSlot(n.Name), len(n.Params), c.token(n.token))
+		return nil
+	}
+
+	// a variable
+	if slot, ok := c.locals[n.Name]; ok {
+		c.emit(opLoadLocal, slot, c.globalSlot(n.Name), c.token(n.token))
+		return nil
+	}
+	c.emit(opLoadGlobal, c.globalSlot(n.Name), c.token(n.token))
+	return nil
+}
+
+/*
+logical compiles && and || so that the right side only runs when the left
+side does not decide, both give a bool:
+
+	a && b                        a || b
+
+	a                             a
+	opJumpIfFalse no              opJumpIfTrue yes
+	b                             b
+	opJumpIfFalse no              opJumpIfTrue yes
+	opConst true                  opConst false
+	opJump end                    opJump end
+	no: opConst false             yes: opConst true
+	end:                          end:
+*/
+func (c *compiler) logical(n *Node) error {
+	jump, decided := opJumpIfFalse, BoolValue(false)
+	if n.token.kind == tOr {
+		jump, decided = opJumpIfTrue, BoolValue(true)
+	}
+	if err := c.expression(&n.Params[0]); err != nil {
+		return err
+	}
+	left := c.emitJump(jump)
+	if err := c.expression(&n.Params[1]); err != nil {
+		return err
+	}
+	right := c.emitJump(jump)
+	c.emit(opConst, c.constant(BoolValue(!decided.truthy())))
+	end := c.emitJump(opJump)
+	c.patch(left)
+	c.patch(right)
+	c.emit(opConst, c.constant(decided))
+	c.patch(end)
+	return nil
+}
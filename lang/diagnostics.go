@@ -0,0 +1,307 @@
+/**
+ * ============================================================================
+ *                                  (ノ°Д°）ノ
+ *                               THE DIAGNOSTICS
+ * ============================================================================
+ */
+
+package lang
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+/*
+A Diagnostic is an error of any stage, lexer, parser, semantic or runtime, at
+a place of a file. The same diagnostics are shown to people the way the
+compilers do, with the line of the source and the token underlined:
+
+  test.txt:3:9: error: unexpected token `b`, should be `,` or `)`
+      3 | print(a b)
+        |         ^
+  note: expected `)` to close `(` opened at 3:6
+
+and written as JSON or SARIF for the tools.
+*/
+
+// codes of the diagnostics, the part before the / is the stage finding them
+const (
+	codeInvalidCharacter      = "lexer/invalid-character"
+	codeUnterminatedString    = "lexer/unterminated-string"
+	codeInvalidEscape         = "lexer/invalid-escape"
+	codeUnterminatedComment   = "lexer/unterminated-comment"
+	codeNestedComment         = "lexer/nested-comment"
+	codeUnexpectedToken       = "parser/unexpected-token"
+	codeInvalidInt            = "parser/invalid-int"
+	codeInvalidFloat          = "parser/invalid-float"
+	codeInvalidAssignment     = "parser/invalid-assignment"
+	codeBuiltinRedeclared     = "semantic/builtin-redeclared"
+	codeDuplicateParam        = "semantic/duplicate-param"
+	codeReturnOutsideFunction = "semantic/return-outside-function"
+	codeBreakOutsideLoop      = "semantic/break-outside-loop"
+	codeContinueOutsideLoop   = "semantic/continue-outside-loop"
+	codeUndefinedLabel        = "semantic/undefined-label"
+	codeDuplicateDefault      = "semantic/duplicate-default"
+	codeUndefinedVariable     = "runtime/undefined-variable"
+	codeUndefinedFunction     = "runtime/undefined-function"
+	codeWrongArity            = "runtime/wrong-arity"
+	codeStackOverflow         = "runtime/stack-overflow"
+	codeTypeError             = "runtime/type-error"
+	codeDivisionByZero        = "runtime/division-by-zero"
+	codeIndexOutOfRange       = "runtime/index-out-of-range"
+	codeKeyNotFound           = "runtime/key-not-found"
+	codeConversion            = "runtime/conversion"
+	codeHostError             = "runtime/host-error"
+	codeInternal              = "internal/error"
+	// codeError is an error that is not about the source, like a missing file
+	codeError = "error"
+)
+
+// Diagnostic is a problem of a file. The lines and columns start at 1, the
+// end is the column after the last character, and Line is 0 for the errors
+// that are not at a place of the file.
+type Diagnostic struct {
+	Severity string   `json:"severity"`
+	Code     string   `json:"code"`
+	File     string   `json:"file"`
+	Line     int      `json:"line"`
+	Col      int      `json:"column"`
+	EndLine  int      `json:"endLine"`
+	EndCol   int      `json:"endColumn"`
+	Message  string   `json:"message"`
+	Notes    []string `json:"notes,omitempty"`
+}
+
+// Diagnostics gives back the diagnostics of err about file, one for every
+// error of an ErrorList
+func Diagnostics(file string, err error) []Diagnostic {
+	var list ErrorList
+	if errors.As(err, &list) {
+		var diagnostics []Diagnostic
+		for _, err := range list {
+			diagnostics = append(diagnostics, Diagnostics(file, err)...)
+		}
+		return diagnostics
+	}
+	d := Diagnostic{Severity: "error", Code: codeError, File: file, Message: err.Error()}
+	var syntaxErr *SyntaxError
+	var runtimeErr *RuntimeError
+	switch {
+	case errors.As(err, &syntaxErr):
+		d.Code = syntaxErr.Code
+		d.Line, d.Col = syntaxErr.Line, syntaxErr.Col
+		d.EndLine, d.EndCol = syntaxErr.Line, syntaxErr.Col+tokenWidth(syntaxErr.Found)
+		d.Message = syntaxErr.reason()
+		d.Notes = syntaxErr.Notes
+	case errors.As(err, &runtimeErr):
+		t := runtimeErr.token
+		d.Code = runtimeErr.code
+		d.Line, d.Col = t.line, t.col
+		d.EndLine, d.EndCol = t.line, t.col+tokenWidth(t)
+		d.Message = runtimeErr.message
+		for i, entry := range runtimeErr.trace {
+			if i == maxTrace {
+				d.Notes = append(d.Notes, fmt.Sprintf("... %d more calls", len(runtimeErr.trace)-maxTrace))
+				break
+			}
+			d.Notes = append(d.Notes, fmt.Sprintf("in %s called at %d:%d", entry.function, entry.call.line, entry.call.col))
+		}
+	}
+	return []Diagnostic{d}
+}
+
+// tokenWidth is how many columns of the source the token takes
+func tokenWidth(t Token) int {
+	if t.kind == 0 && t.value == "" || t.kind == tNewLine {
+		return 1
+	}
+	return len(tokenText(t))
+}
+
+// ANSI colors of the parts of a diagnostic
+const (
+	colorReset = "\x1b[0m"
+	colorBold  = "\x1b[1m"
+	colorError = "\x1b[1;31m"
+	colorCaret = "\x1b[1;32m"
+	colorNote  = "\x1b[1;36m"
+)
+
+// RenderDiagnostics writes the diagnostics of the source for people, with
+// ANSI colors if color is set
+func RenderDiagnostics(w io.Writer, source []byte, diagnostics []Diagnostic, color bool) {
+	paint := func(code string, text string) string {
+		if !color {
+			return text
+		}
+		return code + text + colorReset
+	}
+	lines := strings.Split(string(source), "\n")
+
+	for _, d := range diagnostics {
+		severity := paint(colorError, d.Severity+":")
+		if d.Line <= 0 {
+			fmt.Fprintf(w, "%s %s %s\n", paint(colorBold, d.File+":"), severity, d.Message)
+			continue
+		}
+		fmt.Fprintf(w, "%s %s %s\n", paint(colorBold, fmt.Sprintf("%s:%d:%d:", d.File, d.Line, d.Col)), severity, d.Message)
+
+		if d.Line <= len(lines) {
+			line := strings.TrimRight(lines[d.Line-1], "\r")
+			number := fmt.Sprint(d.Line)
+			gutter := strings.Repeat(" ", len(number))
+			endCol := d.EndCol
+			if d.EndLine != d.Line {
+				endCol = len(line) + 1
+			}
+			fmt.Fprintf(w, "    %s | %s\n", number, line)
+			fmt.Fprintf(w, "    %s | %s%s\n", gutter, caretIndent(line, d.Col), paint(colorCaret, underline(line, d.Col, endCol)))
+		}
+		for _, note := range d.Notes {
+			fmt.Fprintf(w, "%s %s\n", paint(colorNote, "note:"), note)
+		}
+	}
+}
+
+// caretIndent are the blanks in front of the caret, the tabs of the line are
+// kept so the caret lines up with the token
+func caretIndent(line string, col int) string {
+	end := col - 1
+	if end > len(line) {
+		end = len(line)
+	}
+	var b strings.Builder
+	for _, r := range line[:end] {
+		if r == '\t' {
+			b.WriteByte('\t')
+		} else {
+			b.WriteByte(' ')
+		}
+	}
+	// the end of the tokens is one past the end of the line
+	for i := len(line); i < col-1; i++ {
+		b.WriteByte(' ')
+	}
+	return b.String()
+}
+
+// underline is the `^~~~` under the characters of the line from col up to
+// endCol
+func underline(line string, col int, endCol int) string {
+	length := 1
+	if col-1 < len(line) {
+		if endCol-1 > len(line) {
+			endCol = len(line) + 1
+		}
+		if endCol > col {
+			length = utf8.RuneCountInString(line[col-1 : endCol-1])
+		}
+	}
+	return "^" + strings.Repeat("~", length-1)
+}
+
+// WriteDiagnosticsJSON writes the diagnostics as a JSON array
+func WriteDiagnosticsJSON(w io.Writer, diagnostics []Diagnostic) error {
+	if diagnostics == nil {
+		diagnostics = []Diagnostic{}
+	}
+	j, err := json.MarshalIndent(diagnostics, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "%s\n", j)
+	return err
+}
+
+// the parts of a SARIF 2.1.0 log we write
+type (
+	sarifLog struct {
+		Version string     `json:"version"`
+		Schema  string     `json:"$schema"`
+		Runs    []sarifRun `json:"runs"`
+	}
+	sarifRun struct {
+		Tool    sarifTool     `json:"tool"`
+		Results []sarifResult `json:"results"`
+	}
+	sarifTool struct {
+		Driver sarifDriver `json:"driver"`
+	}
+	sarifDriver struct {
+		Name  string      `json:"name"`
+		Rules []sarifRule `json:"rules"`
+	}
+	sarifRule struct {
+		ID string `json:"id"`
+	}
+	sarifResult struct {
+		RuleID    string          `json:"ruleId"`
+		Level     string          `json:"level"`
+		Message   sarifMessage    `json:"message"`
+		Locations []sarifLocation `json:"locations"`
+	}
+	sarifMessage struct {
+		Text string `json:"text"`
+	}
+	sarifLocation struct {
+		PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+	}
+	sarifPhysicalLocation struct {
+		ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+		Region           *sarifRegion          `json:"region,omitempty"`
+	}
+	sarifArtifactLocation struct {
+		URI string `json:"uri"`
+	}
+	sarifRegion struct {
+		StartLine   int `json:"startLine"`
+		StartColumn int `json:"startColumn"`
+		EndLine     int `json:"endLine"`
+		EndColumn   int `json:"endColumn"`
+	}
+)
+
+// WriteDiagnosticsSARIF writes the diagnostics as a SARIF 2.1.0 log, the
+// notes are added to the message
+func WriteDiagnosticsSARIF(w io.Writer, tool string, diagnostics []Diagnostic) error {
+	run := sarifRun{
+		Tool:    sarifTool{sarifDriver{Name: tool, Rules: []sarifRule{}}},
+		Results: []sarifResult{},
+	}
+	rules := map[string]bool{}
+	for _, d := range diagnostics {
+		rules[d.Code] = true
+		location := sarifPhysicalLocation{ArtifactLocation: sarifArtifactLocation{URI: d.File}}
+		if d.Line > 0 {
+			location.Region = &sarifRegion{d.Line, d.Col, d.EndLine, d.EndCol}
+		}
+		run.Results = append(run.Results, sarifResult{
+			RuleID:    d.Code,
+			Level:     d.Severity,
+			Message:   sarifMessage{strings.Join(append([]string{d.Message}, d.Notes...), "\n")},
+			Locations: []sarifLocation{{location}},
+		})
+	}
+	for code := range rules {
+		run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, sarifRule{code})
+	}
+	sort.Slice(run.Tool.Driver.Rules, func(i, j int) bool {
+		return run.Tool.Driver.Rules[i].ID < run.Tool.Driver.Rules[j].ID
+	})
+	j, err := json.MarshalIndent(sarifLog{
+		Version: "2.1.0",
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Runs:    []sarifRun{run},
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "%s\n", j)
+	return err
+}
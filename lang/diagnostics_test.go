@@ -0,0 +1,202 @@
+package lang
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// diagnose runs the source on the tree backend and gives back what went wrong
+func diagnose(t *testing.T, source string) []Diagnostic {
+	t.Helper()
+	var out bytes.Buffer
+	_, err := NewInterpreter(&out).Eval([]byte(source))
+	if err == nil {
+		t.Fatalf("Eval(%q) did not fail", source)
+	}
+	return Diagnostics("test.txt", err)
+}
+
+// render renders the diagnostics of the source
+func render(t *testing.T, source string, color bool) string {
+	t.Helper()
+	var b strings.Builder
+	RenderDiagnostics(&b, []byte(source), diagnose(t, source), color)
+	return b.String()
+}
+
+func TestRenderDiagnostics(t *testing.T) {
+	tests := []struct {
+		source string
+		want   string
+	}{
+		{
+			"a = 1\nprint(a b)",
+			"test.txt:2:9: error: unexpected token `b`, should be `,` or `)`\n" +
+				"    2 | print(a b)\n" +
+				"      |         ^\n" +
+				"note: expected `)` to close `(` opened at 2:6\n",
+		},
+		{
+			"if (a) {\n\tb = c + * 1\n\tb = 2",
+			"test.txt:2:10: error: unexpected token `*`, should be an expression\n" +
+				"    2 | \tb = c + * 1\n" +
+				"      | \t        ^\n" +
+				"test.txt:3:7: error: unexpected end of tokens, should be `}`\n" +
+				"    3 | \tb = 2\n" +
+				"      | \t     ^\n" +
+				"note: expected `}` to close `{` opened at 1:8\n",
+		},
+		{
+			"a = \"héllo\" 1",
+			"test.txt:1:14: error: unexpected token `1`, should be the end of the statement\n" +
+				"    1 | a = \"héllo\" 1\n" +
+				"      |             ^\n",
+		},
+		{
+			"while (1) { print(undefined_variable) }",
+			"test.txt:1:19: error: undefined variable undefined_variable\n" +
+				"    1 | while (1) { print(undefined_variable) }\n" +
+				"      |                   ^~~~~~~~~~~~~~~~~~\n",
+		},
+		{
+			"int f(int n) {\n  return n / 0\n}\nint g() { return f(1) }\ng()",
+			"test.txt:2:12: error: division by zero\n" +
+				"    2 |   return n / 0\n" +
+				"      |            ^\n" +
+				"note: in f called at 4:18\n" +
+				"note: in g called at 5:1\n",
+		},
+		{
+			"a = [1\nprint(a)",
+			"test.txt:2:1: error: unexpected token `print`, should be `,` or `]`\n" +
+				"    2 | print(a)\n" +
+				"      | ^~~~~\n" +
+				"note: expected `]` to close `[` opened at 1:5\n",
+		},
+		{
+			"print(a[1)",
+			"test.txt:1:10: error: unexpected token `)`, should be `]`\n" +
+				"    1 | print(a[1)\n" +
+				"      |          ^\n" +
+				"note: expected `]` to close `[` opened at 1:8\n",
+		},
+		{
+			"a = 1 /* never closed",
+			"test.txt:1:7: error: unterminated block comment\n" +
+				"    1 | a = 1 /* never closed\n" +
+				"      |       ^~\n",
+		},
+	}
+	for _, tt := range tests {
+		if got := render(t, tt.source, false); got != tt.want {
+			t.Errorf("render(%q) =\n%s\nwant\n%s", tt.source, got, tt.want)
+		}
+	}
+}
+
+func TestRenderDiagnosticsColor(t *testing.T) {
+	got := render(t, "a = 1 +", true)
+	want := "\x1b[1mtest.txt:1:8:\x1b[0m \x1b[1;31merror:\x1b[0m unexpected end of tokens, should be an expression\n" +
+		"    1 | a = 1 +\n" +
+		"      |        \x1b[1;32m^\x1b[0m\n"
+	if got != want {
+		t.Errorf("render with colors =\n%q\nwant\n%q", got, want)
+	}
+}
+
+func TestDiagnosticCodes(t *testing.T) {
+	tests := []struct {
+		source string
+		code   string
+	}{
+		{"a = 1 # 2", "lexer/invalid-character"},
+		{"a = \"never closed", "lexer/unterminated-string"},
+		{"/* never closed", "lexer/unterminated-comment"},
+		{"/* a /* b */ */", "lexer/nested-comment"},
+		{"a = 1 +", "parser/unexpected-token"},
+		{"int len(int a) { return a }", "semantic/builtin-redeclared"},
+		{"return 1", "semantic/return-outside-function"},
+		{"if (a) { break }", "semantic/break-outside-loop"},
+		{"while (a) {}\ncontinue", "semantic/continue-outside-loop"},
+		{"for (;;) { break outer }", "semantic/undefined-label"},
+		{"switch (a) {\ndefault:\ndefault:\n}", "semantic/duplicate-default"},
+		{"switch (a) { case 1: int f() { break } }", "semantic/break-outside-loop"},
+		{"switch (a) { default: continue }", "semantic/continue-outside-loop"},
+		{"print(b)", "runtime/undefined-variable"},
+		{"f()", "runtime/undefined-function"},
+		{"len(1, 2)", "runtime/wrong-arity"},
+		{"a = 1 / 0", "runtime/division-by-zero"},
+		{"a = 1 + \"a\"", "runtime/type-error"},
+		{"a = int(\"x\")", "runtime/conversion"},
+		{"a = [1]\nb = a[0 + 1]", "runtime/index-out-of-range"},
+		{"m = {}\nb = m[\"k\"]", "runtime/key-not-found"},
+	}
+	for _, tt := range tests {
+		diagnostics := diagnose(t, tt.source)
+		if len(diagnostics) == 0 || diagnostics[0].Code != tt.code {
+			t.Errorf("%q gave %+v, want the code %s", tt.source, diagnostics, tt.code)
+		}
+	}
+}
+
+func TestDiagnosticsJSON(t *testing.T) {
+	var b strings.Builder
+	if err := WriteDiagnosticsJSON(&b, diagnose(t, "a = 1\nprint(a b)")); err != nil {
+		t.Fatal(err)
+	}
+	var got []map[string]any
+	if err := json.Unmarshal([]byte(b.String()), &got); err != nil {
+		t.Fatalf("%v in\n%s", err, b.String())
+	}
+	want := map[string]any{
+		"severity": "error", "code": "parser/unexpected-token", "file": "test.txt",
+		"line": 2.0, "column": 9.0, "endLine": 2.0, "endColumn": 10.0,
+		"message": "unexpected token `b`, should be `,` or `)`",
+	}
+	if len(got) != 1 {
+		t.Fatalf("got %d diagnostics, want 1", len(got))
+	}
+	for key, value := range want {
+		if got[0][key] != value {
+			t.Errorf("%s = %v, want %v", key, got[0][key], value)
+		}
+	}
+
+	b.Reset()
+	WriteDiagnosticsJSON(&b, nil)
+	if b.String() != "[]\n" {
+		t.Errorf("no diagnostics gave %q, want an empty array", b.String())
+	}
+
+	// the errors that are not about the source have no place
+	d := Diagnostics("nope.txt", errors.New("open nope.txt: no such file"))
+	if len(d) != 1 || d[0].Code != "error" || d[0].Line != 0 {
+		t.Errorf("a plain error gave %+v", d)
+	}
+}
+
+func TestDiagnosticsSARIF(t *testing.T) {
+	var b strings.Builder
+	if err := WriteDiagnosticsSARIF(&b, "goCompiler", diagnose(t, "print(undefined_variable)")); err != nil {
+		t.Fatal(err)
+	}
+	var log sarifLog
+	if err := json.Unmarshal([]byte(b.String()), &log); err != nil {
+		t.Fatalf("%v in\n%s", err, b.String())
+	}
+	if log.Version != "2.1.0" || len(log.Runs) != 1 || len(log.Runs[0].Results) != 1 {
+		t.Fatalf("unexpected log\n%s", b.String())
+	}
+	run := log.Runs[0]
+	if len(run.Tool.Driver.Rules) != 1 || run.Tool.Driver.Rules[0].ID != "runtime/undefined-variable" {
+		t.Errorf("rules = %+v", run.Tool.Driver.Rules)
+	}
+	result := run.Results[0]
+	region := result.Locations[0].PhysicalLocation.Region
+	if result.RuleID != "runtime/undefined-variable" || region == nil || *region != (sarifRegion{1, 7, 1, 25}) {
+		t.Errorf("result = %+v, region = %+v", result, region)
+	}
+}
@@ -0,0 +1,8 @@
+/*
+Package lang is the language of goCompiler: the tokenizer, the parser, the
+formatter and the two backends that run the ast, a tree-walking Interpreter
+and a bytecode VM. Go programs embed it through NewInterpreter or NewVM and
+give the scripts functions and globals with RegisterFunc and SetGlobal, see
+host.go.
+*/
+package lang
@@ -0,0 +1,208 @@
+/**
+ * ============================================================================
+ *                                   ( ˘ ³˘)♥
+ *                                THE FORMATTER
+ * ============================================================================
+ */
+
+package lang
+
+import (
+	"bytes"
+	"strings"
+)
+
+/*
+The formatter works on the token stream instead of the AST, so it never loses
+anything the user wrote, comments included, even when the program does not
+parse. It only decides where the blanks, the line breaks and the indentation
+go:
+
+  if(a<0){print(a)}else{print(b)}
+
+becomes
+
+  if (a < 0) {
+  	print(a)
+  } else {
+  	print(b)
+  }
+*/
+
+// Format pretty-prints the tokens back to source code
+func Format(tokens []Token) []byte {
+	var b bytes.Buffer
+	depth := 0
+	// blank lines in a row, we keep at most one of them
+	blankLines := 0
+	lineStart := true
+	var prev Token
+	// prev is a prefix operator like the `-` of `-a`, it sticks to the next token
+	prefix := false
+	// open `(`, `[` and map `{`, the lines inside of them are indented once
+	// more
+	groups := 0
+	// maps tells for every open `{` whether it is a map and not a block
+	var maps []bool
+	prevOpensMap := false
+	// we are between a `case` or `default` and the `:` ending it, a `{` right
+	// after that `:` is a block
+	caseHeader := false
+	prevEndsCase := false
+
+	newLine := func() {
+		b.WriteByte('\n')
+		lineStart = true
+	}
+
+	for i := 0; i < len(tokens); i++ {
+		t := tokens[i]
+		// a map `{` and its `}` are laid out like a `[` and its `]`
+		isMap := false
+		switch t.kind {
+		case tLBrace:
+			isMap = !prevEndsCase && opensMap(prev, lineStart, groups)
+			maps = append(maps, isMap)
+		case tRBrace:
+			if len(maps) > 0 {
+				isMap = maps[len(maps)-1]
+				maps = maps[:len(maps)-1]
+			}
+		}
+
+		switch {
+		case t.kind == tNewLine:
+			if lineStart {
+				blankLines++
+				if blankLines > 1 || b.Len() == 0 {
+					continue
+				}
+			}
+			newLine()
+			continue
+
+		case t.kind == tRBrace && !isMap:
+			if depth > 0 {
+				depth--
+			}
+			if !lineStart {
+				newLine()
+			}
+
+		// every case starts a line of its own, like the one after a `{`
+		case (t.kind == tCase || t.kind == tDefault) && !lineStart:
+			newLine()
+		}
+
+		closesGroup := t.kind == tRParen || t.kind == tRBracket || isMap && t.kind == tRBrace
+		if lineStart {
+			indent := depth + groups
+			// `case` and `default` line up with their `switch`
+			if (closesGroup || t.kind == tCase || t.kind == tDefault) && indent > 0 {
+				indent--
+			}
+			b.WriteString(strings.Repeat("\t", indent))
+		} else if prevOpensMap || isMap && t.kind == tRBrace {
+			// no blanks inside of `{"a": 1}`
+		} else if (!prefix || prev.kind == t.kind && t.kind != tNot) && needSpace(prev, t) {
+			// `- -a` would be `--a`, `!!a` stays
+			b.WriteByte(' ')
+		}
+		prefix = prefixOperators[t.kind] && (lineStart || !endsOperand(prev))
+		switch {
+		case t.kind == tLParen || t.kind == tLBracket || isMap && t.kind == tLBrace:
+			groups++
+		case closesGroup:
+			if groups > 0 {
+				groups--
+			}
+		}
+		b.WriteString(tokenText(t))
+		lineStart = false
+		blankLines = 0
+		prev = t
+		prevOpensMap = isMap && t.kind == tLBrace
+		prevEndsCase = caseHeader && t.kind == tColon && groups == 0
+		switch {
+		case t.kind == tCase || t.kind == tDefault:
+			caseHeader = true
+		case prevEndsCase:
+			caseHeader = false
+		}
+
+		// `{` and `}` of a block always end the line, unless the next token does
+		// it anyway, we are in the middle of `} else {` or a comment stays on
+		// the line
+		if isMap {
+			continue
+		}
+		if t.kind == tLBrace {
+			depth++
+		}
+		if t.kind == tLBrace || t.kind == tRBrace {
+			if i+1 < len(tokens) && tokens[i+1].kind != tNewLine && tokens[i+1].kind != tElse &&
+				!(tokens[i+1].kind == tComment && tokens[i+1].line == t.line) {
+				newLine()
+			}
+		}
+	}
+	if !lineStart {
+		b.WriteByte('\n')
+	}
+	return b.Bytes()
+}
+
+// needSpace tells whether a blank goes between two tokens on the same line
+func needSpace(prev Token, curr Token) bool {
+	switch {
+	case curr.kind == tRParen || curr.kind == tRBracket || curr.kind == tComma || curr.kind == tColon || curr.kind == tBreak || curr.kind == tDot:
+		return false
+	case prev.kind == tLParen || prev.kind == tLBracket || prev.kind == tDot:
+		return false
+	case curr.kind == tLBracket:
+		// indexing like `a[i]`, a list like `[1]` gets its blank
+		return !endsOperand(prev)
+	case curr.kind == tIncrement || curr.kind == tDecrement:
+		return false
+	case curr.kind == tLParen:
+		// calling a function like `print(a)`
+		return prev.kind != tIdentifier && prev.kind != tPrint && prev.kind != tRParen
+	}
+	return true
+}
+
+// opensMap tells whether a `{` after prev is a map, a `{` is a block when it
+// starts a line or comes after the `)` of an if, a while, a for or a function,
+// an else, or another block
+func opensMap(prev Token, lineStart bool, groups int) bool {
+	if groups > 0 {
+		return true
+	}
+	if lineStart {
+		return false
+	}
+	switch prev.kind {
+	case tRParen, tElse, tLBrace, tRBrace, tBreak, tComment:
+		return false
+	}
+	return true
+}
+
+// endsOperand tells whether an operand can end with the token, so that a `-`
+// after it is the binary one
+func endsOperand(t Token) bool {
+	switch t.kind {
+	case tIdentifier, tInteger, tFloat, tString, tRParen, tRBracket, tIncrement, tDecrement:
+		return true
+	}
+	return false
+}
+
+// tokenText gives back the source code of a token
+func tokenText(t Token) string {
+	switch t.kind {
+	case tNewLine:
+		return "\n"
+	}
+	return t.value
+}
@@ -0,0 +1,32 @@
+package lang
+
+import "testing"
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		source string
+		want   string
+	}{
+		{"if(a<0){print(a)}else{print(b)}", "if (a < 0) {\n\tprint(a)\n} else {\n\tprint(b)\n}\n"},
+		{"a=-5\nb=3*-a\nc=- -a\nd=!!a", "a = -5\nb = 3 * -a\nc = - -a\nd = !!a\n"},
+		{"for(i=0;i<3;i++){x+=1}", "for (i = 0; i < 3; i++) {\n\tx += 1\n}\n"},
+		{"l=[1,[2,3]]\nl[0]=l[1][0]", "l = [1, [2, 3]]\nl[0] = l[1][0]\n"},
+		{"m={\"a\":1,\"b\":{}}", "m = {\"a\": 1, \"b\": {}}\n"},
+		{"if(a){m={ \"k\" : 1 }}", "if (a) {\n\tm = {\"k\": 1}\n}\n"},
+		{"{ a = 1 }", "{\n\ta = 1\n}\n"},
+		{"if(a){b=1}else if(c){b=2}else{b=3}", "if (a) {\n\tb = 1\n} else if (c) {\n\tb = 2\n} else {\n\tb = 3\n}\n"},
+		{"switch(a){\ncase 1,2:\nb={}\ndefault:\nprint(a)\n}", "switch (a) {\ncase 1, 2:\n\tb = {}\ndefault:\n\tprint(a)\n}\n"},
+		{"while(a){\nswitch(b){\ncase {\"k\":1}:\ncontinue\n}\n}", "while (a) {\n\tswitch (b) {\n\tcase {\"k\": 1}:\n\t\tcontinue\n\t}\n}\n"},
+		{"switch (x) { case 1, 2: print(\"a\") default: print(\"b\") }", "switch (x) {\ncase 1, 2: print(\"a\")\ndefault: print(\"b\")\n}\n"},
+		{"m = {\n\"a\": [\n1,\n],\n}", "m = {\n\t\"a\": [\n\t\t1,\n\t],\n}\n"},
+	}
+	for _, tt := range tests {
+		tokens, err := TokenizeTrivia([]byte(tt.source))
+		if err != nil {
+			t.Fatalf("TokenizeTrivia(%q): %v", tt.source, err)
+		}
+		if got := string(Format(tokens)); got != tt.want {
+			t.Errorf("Format(%q) =\n%s\nwant\n%s", tt.source, got, tt.want)
+		}
+	}
+}
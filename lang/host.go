@@ -0,0 +1,128 @@
+/**
+ * ============================================================================
+ *                                  ( ・_・)ノ
+ *                                  THE HOST
+ * ============================================================================
+ */
+
+package lang
+
+import "fmt"
+
+/*
+The host is the Go program the language is embedded in. It gives the scripts
+functions to call and reads and writes their globals:
+
+  in := lang.NewInterpreter(os.Stdout)
+  in.RegisterFunc("now", func(args ...lang.Value) (lang.Value, error) {
+  	return lang.IntValue(int(time.Now().Unix())), nil
+  })
+  in.SetGlobal("user", lang.StringValue("ada"))
+  if _, err := in.Eval([]byte("started = now()")); err != nil {
+  	return err
+  }
+  started, _ := in.Global("started")
+
+A call looks for a builtin first, then for a function the script declared and
+only then for a host function, so the host can not change what a script means.
+*/
+
+// HostFunc is a function of the host the scripts can call. An error stops the
+// script with a RuntimeError that wraps it.
+type HostFunc func(args ...Value) (Value, error)
+
+// checkHostName panics when name can never be called as a host function
+func checkHostName(name string) {
+	if lookupBuiltin(name) != nil {
+		panic(fmt.Sprintf("lang: %s is a builtin function", name))
+	}
+}
+
+// callHost calls the host function f on behalf of the call t
+func callHost(f HostFunc, t Token, args []Value) (Value, *RuntimeError) {
+	v, err := f(args...)
+	if err != nil {
+		return Value{}, &RuntimeError{token: t, code: codeHostError, message: err.Error(), err: err}
+	}
+	return v, nil
+}
+
+// RegisterFunc makes f callable from the scripts as name. It panics if name
+// is a builtin.
+func (in *Interpreter) RegisterFunc(name string, f HostFunc) {
+	checkHostName(name)
+	in.host[name] = f
+}
+
+// SetGlobal assigns the global variable name
+func (in *Interpreter) SetGlobal(name string, v Value) {
+	in.variables[name] = v
+}
+
+// Global reads the global variable name, ok is false if it is not assigned
+func (in *Interpreter) Global(name string) (v Value, ok bool) {
+	v, ok = in.variables[name]
+	return v, ok
+}
+
+// Run the ast of a program, the globals and the functions it declares stay
+// for the next run. VM.Run does not keep them, every program it runs starts
+// with the globals of SetGlobal only.
+func (in *Interpreter) Run(ast *Node) (Value, error) {
+	return in.run(ast)
+}
+
+// Eval tokenizes, parses and runs the source, it gives back the value of the
+// last statement
+func (in *Interpreter) Eval(source []byte) (result Value, err error) {
+	tokens, lexErr := Tokenize(source)
+	ast, err := Parse(tokens)
+	if err = JoinErrors(lexErr, err); err != nil {
+		return Value{}, err
+	}
+	for i := range ast.Body {
+		if result, err = in.run(&ast.Body[i]); err != nil {
+			return Value{}, err
+		}
+	}
+	return result, nil
+}
+
+// RegisterFunc makes f callable from the programs as name. It panics if name
+// is a builtin.
+func (m *VM) RegisterFunc(name string, f HostFunc) {
+	checkHostName(name)
+	m.host[name] = f
+}
+
+// SetGlobal assigns the global variable name, a global set before Run is
+// there when the program starts
+func (m *VM) SetGlobal(name string, v Value) {
+	m.hostGlobals[name] = v
+	if slot := m.globalSlot(name); slot >= 0 {
+		m.globals[slot] = v
+		m.defined[slot] = true
+	}
+}
+
+// Global reads the global variable name, ok is false if it is not assigned
+func (m *VM) Global(name string) (v Value, ok bool) {
+	if slot := m.globalSlot(name); slot >= 0 {
+		return m.globals[slot], m.defined[slot]
+	}
+	v, ok = m.hostGlobals[name]
+	return v, ok
+}
+
+// globalSlot finds the slot of a global of the program that runs, or -1
+func (m *VM) globalSlot(name string) int {
+	if m.program == nil {
+		return -1
+	}
+	for slot, global := range m.program.globals {
+		if global == name {
+			return slot
+		}
+	}
+	return -1
+}
@@ -0,0 +1,352 @@
+package lang
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+)
+
+// host is what the tests need of both backends
+type host interface {
+	RegisterFunc(name string, f HostFunc)
+	SetGlobal(name string, v Value)
+	Global(name string) (Value, bool)
+	Reset()
+}
+
+// runHost runs the source on the backend after setup prepared the host
+func runHost(t *testing.T, backend string, source string, setup func(h host)) (host, string, error) {
+	t.Helper()
+	ast, err := parseString(t, source)
+	if err != nil {
+		t.Fatalf("parse(%q): %v", source, err)
+	}
+	var out bytes.Buffer
+	if backend == "vm" {
+		m := NewVM(&out)
+		setup(m)
+		program, err := Compile(&ast)
+		if err != nil {
+			t.Fatalf("compile(%q): %v", source, err)
+		}
+		err = m.Run(program)
+		return m, out.String(), err
+	}
+	in := NewInterpreter(&out)
+	setup(in)
+	_, err = in.Run(&ast)
+	return in, out.String(), err
+}
+
+var errHost = errors.New("the host is down")
+
+func setupHost(h host) {
+	h.RegisterFunc("twice", func(args ...Value) (Value, error) {
+		if len(args) != 1 {
+			return Value{}, fmt.Errorf("twice wants 1 params but got %d", len(args))
+		}
+		i, ok := args[0].Int()
+		if !ok {
+			return Value{}, fmt.Errorf("twice wants an int but got %s", args[0].Type())
+		}
+		return IntValue(2 * i), nil
+	})
+	h.RegisterFunc("fail", func(args ...Value) (Value, error) {
+		return Value{}, errHost
+	})
+	h.RegisterFunc("greeting", func(args ...Value) (Value, error) {
+		return StringValue("hello"), nil
+	})
+	h.SetGlobal("x", IntValue(21))
+}
+
+func TestHostFunctions(t *testing.T) {
+	for _, backend := range []string{"tree", "vm"} {
+		h, out, err := runHost(t, backend, "y = twice(x)\nprint(greeting(), y)\nint f(int a) { return twice(a) + x }\nz = f(1)", setupHost)
+		if err != nil {
+			t.Fatalf("%s: unexpected error %v", backend, err)
+		}
+		if out != "hello 42\n" {
+			t.Errorf("%s printed %q", backend, out)
+		}
+		if y, ok := h.Global("y"); !ok || y != IntValue(42) {
+			t.Errorf("%s: y = %v, %v, want 42", backend, y, ok)
+		}
+		if z, _ := h.Global("z"); z != IntValue(23) {
+			t.Errorf("%s: z = %v, want 23", backend, z)
+		}
+		if _, ok := h.Global("nope"); ok {
+			t.Errorf("%s: nope should not be assigned", backend)
+		}
+	}
+}
+
+func TestHostShadowing(t *testing.T) {
+	for _, backend := range []string{"tree", "vm"} {
+		_, out, err := runHost(t, backend, "int twice(int a) { return a }\nprint(twice(x))", setupHost)
+		if err != nil || out != "21\n" {
+			t.Errorf("%s: the function of the script should win, got %q, %v", backend, out, err)
+		}
+	}
+}
+
+func TestHostErrors(t *testing.T) {
+	for _, backend := range []string{"tree", "vm"} {
+		_, _, err := runHost(t, backend, "print(1)\nfail()", setupHost)
+		var runtimeErr *RuntimeError
+		if !errors.As(err, &runtimeErr) || !errors.Is(err, errHost) {
+			t.Errorf("%s: fail() gave %v, want a RuntimeError wrapping the error of the host", backend, err)
+		}
+		if err != nil && err.Error() != "runtime error: the host is down at line 2, col 1" {
+			t.Errorf("%s: fail() gave %q", backend, err)
+		}
+
+		_, _, err = runHost(t, backend, "twice(\"a\")", setupHost)
+		if err == nil || err.Error() != "runtime error: twice wants an int but got string at line 1, col 1" {
+			t.Errorf("%s: twice(\"a\") gave %v", backend, err)
+		}
+	}
+}
+
+func TestHostBuiltinName(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Errorf("registering print did not panic")
+		}
+	}()
+	NewInterpreter(nil).RegisterFunc("print", func(args ...Value) (Value, error) { return Value{}, nil })
+}
+
+func TestInterpreterEval(t *testing.T) {
+	var out bytes.Buffer
+	in := NewInterpreter(&out)
+	in.SetGlobal("name", StringValue("ada"))
+	if _, err := in.Eval([]byte("greeting = \"hi \"\nprint(name)")); err != nil {
+		t.Fatal(err)
+	}
+	v, err := in.Eval([]byte("len(greeting) + len(name)"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if i, ok := v.Int(); !ok || i != 6 {
+		t.Errorf("Eval gave %v, want 6", v)
+	}
+	if out.String() != "ada\n" {
+		t.Errorf("Eval printed %q", out.String())
+	}
+	if _, err := in.Eval([]byte("1 +")); err == nil {
+		t.Errorf("Eval(\"1 +\") did not fail")
+	}
+	var list ErrorList
+	if _, err := in.Eval([]byte("a = 1 $ 2")); !errors.As(err, &list) || len(list) != 2 {
+		t.Errorf("Eval(\"a = 1 $ 2\") gave %v, want the errors of the tokenizer and of the parser", err)
+	}
+}
+
+func TestHostLists(t *testing.T) {
+	for _, backend := range []string{"tree", "vm"} {
+		h, out, err := runHost(t, backend, "push(items, len(items))\nfirst = items[0]\nprint(items)", func(h host) {
+			h.SetGlobal("items", ListValue(IntValue(1), StringValue("b")))
+		})
+		if err != nil {
+			t.Fatalf("%s: unexpected error %v", backend, err)
+		}
+		if out != "[1, \"b\", 2]\n" {
+			t.Errorf("%s printed %q", backend, out)
+		}
+		items, _ := h.Global("items")
+		if l, ok := items.List(); !ok || len(l) != 3 || l[2] != IntValue(2) {
+			t.Errorf("%s: items = %v, want the list the script pushed to", backend, items)
+		}
+		if first, _ := h.Global("first"); first != IntValue(1) {
+			t.Errorf("%s: first = %v", backend, first)
+		}
+	}
+}
+
+// TestHostCompoundIndexAssignment checks that `a[f()] += 1` calls f once
+func TestHostCompoundIndexAssignment(t *testing.T) {
+	source := "int next() {\n\tpush(calls, len(calls))\n\treturn len(calls) - 1\n}\n" +
+		"counts = [10, 20]\ncounts[next()] += 5\ncounts[next()]++\nm = {\"k\": \"a\"}\nm[\"k\" + str(next())] = 0\nm[\"k\"] += \"b\"\nprint(counts, m, calls)"
+	for _, backend := range []string{"tree", "vm"} {
+		_, out, err := runHost(t, backend, source, func(h host) {
+			h.SetGlobal("calls", ListValue())
+		})
+		if err != nil {
+			t.Fatalf("%s: unexpected error %v", backend, err)
+		}
+		if out != "[15, 21] {\"k\": \"ab\", \"k2\": 0} [0, 1, 2]\n" {
+			t.Errorf("%s printed %q", backend, out)
+		}
+	}
+}
+
+func TestHostMaps(t *testing.T) {
+	for _, backend := range []string{"tree", "vm"} {
+		h, out, err := runHost(t, backend, "config[\"c\"] = config[\"a\"] + 1\nprint(config, keys(config))", func(h host) {
+			h.SetGlobal("config", MapValue(map[string]Value{"b": StringValue("x"), "a": IntValue(1)}))
+		})
+		if err != nil {
+			t.Fatalf("%s: unexpected error %v", backend, err)
+		}
+		if out != "{\"a\": 1, \"b\": \"x\", \"c\": 2} [\"a\", \"b\", \"c\"]\n" {
+			t.Errorf("%s printed %q", backend, out)
+		}
+		config, _ := h.Global("config")
+		keys, values, ok := config.Map()
+		if !ok || len(keys) != 3 || keys[2] != StringValue("c") || values[2] != IntValue(2) {
+			t.Errorf("%s: config = %v", backend, config)
+		}
+	}
+}
+
+// TestHostReset runs two programs on the same host with a Reset in between,
+// nothing of the first one is left for the second one
+func TestHostReset(t *testing.T) {
+	first := "int f() { return 1 }\na = f()"
+	tests := []string{"print(a)", "print(f())", "print(user)"}
+	for _, backend := range []string{"tree", "vm"} {
+		for _, second := range tests {
+			h, _, err := runHost(t, backend, first, func(h host) {
+				h.SetGlobal("user", StringValue("ada"))
+				h.RegisterFunc("now", func(args ...Value) (Value, error) { return IntValue(7), nil })
+			})
+			if err != nil {
+				t.Fatalf("%s: unexpected error %v", backend, err)
+			}
+			h.Reset()
+			if _, ok := h.Global("a"); ok {
+				t.Errorf("%s: a is still set after Reset", backend)
+			}
+			var out bytes.Buffer
+			err = runAgain(t, h, &out, second)
+			var runtimeErr *RuntimeError
+			if !errors.As(err, &runtimeErr) || out.Len() != 0 {
+				t.Errorf("%s: %q after Reset printed %q and gave %v, want an error", backend, second, out.String(), err)
+			}
+			out.Reset()
+			if err := runAgain(t, h, &out, "print(now())"); err != nil || out.String() != "7\n" {
+				t.Errorf("%s: host function after Reset printed %q and gave %v", backend, out.String(), err)
+			}
+		}
+	}
+}
+
+// TestHostRunAgain pins what a second Run sees of the first one: the
+// interpreter keeps the globals of the script, the vm starts over from the
+// ones set with SetGlobal
+func TestHostRunAgain(t *testing.T) {
+	tests := []struct {
+		backend string
+		want    string
+		err     string
+	}{
+		{"tree", "5\n2\n", ""},
+		{"vm", "1\n", "runtime error: undefined variable a at line 2, col 7"},
+	}
+	for _, tt := range tests {
+		h, _, err := runHost(t, tt.backend, "a = base + 1\nbase = 5", func(h host) {
+			h.SetGlobal("base", IntValue(1))
+		})
+		if err != nil {
+			t.Fatalf("%s: unexpected error %v", tt.backend, err)
+		}
+		var out bytes.Buffer
+		got := ""
+		if err := runAgain(t, h, &out, "print(base)\nprint(a)"); err != nil {
+			got = err.Error()
+		}
+		if out.String() != tt.want || got != tt.err {
+			t.Errorf("%s: the second run printed %q and gave %q, want %q and %q", tt.backend, out.String(), got, tt.want, tt.err)
+		}
+	}
+}
+
+// runAgain runs the source on a host that already ran, printing to out
+func runAgain(t *testing.T, h host, out *bytes.Buffer, source string) error {
+	t.Helper()
+	ast, err := parseString(t, source)
+	if err != nil {
+		t.Fatalf("parse(%q): %v", source, err)
+	}
+	switch h := h.(type) {
+	case *VM:
+		h.out = out
+		program, err := Compile(&ast)
+		if err != nil {
+			t.Fatalf("compile(%q): %v", source, err)
+		}
+		return h.Run(program)
+	case *Interpreter:
+		h.out = out
+		_, err := h.Run(&ast)
+		return err
+	}
+	t.Fatalf("unknown host %T", h)
+	return nil
+}
+
+// TestHostIsolation runs different programs on many interpreters and vms at
+// the same time, the globals, the functions and the output of one never show
+// up in another. Run it with -race.
+func TestHostIsolation(t *testing.T) {
+	// the programs are parsed and compiled up front, the goroutines can not
+	// stop the test with t.Fatalf
+	asts := make([]Node, 16)
+	programs := make([]*Program, len(asts))
+	for n := range asts {
+		source := fmt.Sprintf("int f() { return %d }\nx = f() + id() + base\nfor (i = 0; i < 100; i++) { x++ }\nprint(x)", n)
+		ast, err := parseString(t, source)
+		if err != nil {
+			t.Fatalf("parse(%q): %v", source, err)
+		}
+		if programs[n], err = Compile(&ast); err != nil {
+			t.Fatalf("compile(%q): %v", source, err)
+		}
+		asts[n] = ast
+	}
+
+	var wg sync.WaitGroup
+	for n := range asts {
+		wg.Add(1)
+		go func(n int) {
+			defer wg.Done()
+			var out bytes.Buffer
+			var h host
+			var err error
+			backend := []string{"tree", "vm"}[n%2]
+			if backend == "vm" {
+				m := NewVM(&out)
+				h = m
+				setupIsolated(h, n)
+				err = m.Run(programs[n])
+			} else {
+				in := NewInterpreter(&out)
+				h = in
+				setupIsolated(h, n)
+				_, err = in.Run(&asts[n])
+			}
+			if err != nil {
+				t.Errorf("%s %d: unexpected error %v", backend, n, err)
+				return
+			}
+			want := 1000*n + 2*n + 100
+			if out.String() != fmt.Sprintf("%d\n", want) {
+				t.Errorf("%s %d printed %q, want %d", backend, n, out.String(), want)
+			}
+			if x, _ := h.Global("x"); x != IntValue(want) {
+				t.Errorf("%s %d: x = %v, want %d", backend, n, x, want)
+			}
+		}(n)
+	}
+	wg.Wait()
+}
+
+// setupIsolated gives the host of the program n of TestHostIsolation its own
+// global and function
+func setupIsolated(h host, n int) {
+	h.SetGlobal("base", IntValue(1000*n))
+	h.RegisterFunc("id", func(args ...Value) (Value, error) { return IntValue(n), nil })
+}
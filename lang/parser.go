@@ -0,0 +1,1397 @@
+/**
+ * ============================================================================
+ *                                 ヽ/❀o ل͜ o\ﾉ
+ *                                THE PARSER!!!
+ * ============================================================================
+ */
+
+package lang
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+/*
+We will define our type, `node` here. Within Node are pointers types to what
+would otherwise be recursive types in Go. e.g.
+
+callee    Node
+
+Would cause the Go compiler to complain about a recursive type. When we want
+to use one of these types to pass through to a function, for example, we'd
+use `&` as it'd be a reference. But we'll come to that a bit later on.
+*/
+
+type Node struct {
+	Kind   int
+	Value  string
+	Name   string
+	token  Token // 打印错误信息用
+	Body   []Node
+	Params []Node
+	//callee     *node
+	//expression *node
+	//arguments  *[]node
+	//context    *[]node
+}
+
+/* 文法定义
+Program     -> StatementList
+StatementList -> Statement StatementList | ε
+Statement   -> AssignmentStatement | PrintStatement | IfStatement | WhileStatement | ForStatement
+             | SwitchStatement
+             | FunctionDeclaration | ReturnStatement | BreakStatement | ContinueStatement
+             | Identifier: WhileStatement | Identifier: ForStatement
+AssignmentStatement -> Identifier = Expression
+PrintStatement -> print(ArgumentList)
+IfStatement  -> if(Expression) {Statement} | if(Expression) {Statement} else {Statement}
+             | if(Expression) {Statement} else IfStatement
+SwitchStatement -> switch(Expression) {CaseList}
+CaseList    -> case ArgumentList: StatementList CaseList | default: StatementList CaseList | ε
+                                 a default at most, no case falls through.
+                                 Like in C and Go, a break without a label
+                                 ends the switch and a continue goes to the
+                                 loop around it
+WhileStatement -> while(Expression) {Statement}
+ForStatement -> for(Statement; Expression; Statement) {Statement}
+FunctionDeclaration -> Identifier Identifier(ParameterList) {Statement}
+ParameterList -> Identifier Identifier | Identifier Identifier, ParameterList | ε
+ReturnStatement -> return | return Expression
+BreakStatement -> break | break Identifier         only inside of a loop or a
+ContinueStatement -> continue | continue Identifier   switch for break, the
+                                                      identifier is the label of
+                                                      a loop
+Expression  -> UnaryExpression | BinaryExpression | ParenthesizedExpression | Identifier | Literal
+             | CallExpression
+CallExpression -> Identifier(ArgumentList)
+ArgumentList -> Expression | Expression, ArgumentList | ε
+UnaryExpression   -> Operator Expression
+BinaryExpression  -> Expression Operator Expression
+ParenthesizedExpression -> (Expression)
+Identifier  -> (a-zA-Z_)[a-zA-Z0-9_]*
+Literal     -> Number | String | true | false | nil | List
+List        -> [ArgumentList]
+Map         -> {EntryList}       only where an expression goes, a `{` that
+                                 starts a statement is a block
+EntryList   -> Expression: Expression | Expression: Expression, EntryList | ε
+IndexExpression -> Expression[Expression]
+Number      -> [0-9]+ | Float
+Float       -> [0-9]+(.[0-9]+)?([eE][+-]?[0-9]+)?
+String      -> "([^"\\\n] | \Escape)*"
+Escape      -> n | t | r | " | \\ | u{[0-9a-fA-F]+}
+*/
+
+// kind of ast
+// aProgram -> aStatement | aExpression
+// aExpression -> + | - | * | / | Function
+// aStatement -> (If | Else | For) [aExpression] {aStatement}
+// aNumberLiteral -> tInteger
+// aFloatLiteral -> tFloat
+const (
+	//aBlank 空白标识符
+	aBlank = 100 + iota
+	//aProgram 一个子程序
+	aProgram
+
+	//aExpression 语法表达式，即语法。
+	//是指一个计算值的代码片段，它可以由变量引用、数值计算、函数调用等组成。
+	//表达式通常会产生一个值，并可以用于组成更复杂的表达式或用于赋值给某个变量。
+	// 在params中解析
+	aExpression
+
+	//aStatement 语法树节点，即语句。
+	//是指一条执行操作或者完成某个动作的代码指令。
+	//语句不一定产生一个值，它可以是赋值、条件判断、循环等。
+	//语句用于组织代码的执行顺序，使程序按照预期的逻辑执行。
+	// 通常在body中解析
+	aStatement
+
+	//有参数的语句块，例如if(){}else{}
+	/*var ifNode = Node{
+		kind: aStatementWithBodyAndParams,
+		name: "if",
+		params: []node{
+			{
+				kind: aExpression,
+			},
+		},
+		body: make([]node, 2),
+	}*/
+	aStatementIf
+	aStatementFor
+	aStatementWhile
+	//aStatementReturn 返回语句，params里是返回值
+	aStatementReturn
+
+	//aFunction 函数定义，value是返回类型，params是参数，body是函数体
+	/*var fNode = Node{
+		kind:  aFunction,
+		name:  "f",
+		value: "int",
+		params: []node{
+			{kind: aExpression, name: "a", value: "int"},
+			{kind: aExpression, name: "b", value: "int"},
+		},
+		body: []node{{kind: aStatement}},
+	}*/
+	aFunction
+
+	//aAssignmentStatement 赋值语句
+	aAssignmentStatement
+
+	//aLiteral 一个字面量（常量）
+	//aNumberLiteral 一个数字字面量
+	aNumberLiteral
+	//aStringLiteral 一个字符串字面量
+	aStringLiteral
+	//aBoolLiteral 一个布尔字面量，true 或 false
+	aBoolLiteral
+	//aNilLiteral 空值 nil
+	aNilLiteral
+	//aFloatLiteral 一个浮点数字面量，例如 3.14 或 1e-3
+	aFloatLiteral
+	//aListLiteral 一个列表字面量，params是元素
+	aListLiteral
+	//aIndexExpression 下标 a[i]，params是列表和下标，token是下标的第一个token
+	aIndexExpression
+	//aMapLiteral 一个字典字面量，params是键和值交替排列 k1 v1 k2 v2
+	aMapLiteral
+	//aStatementBreak 跳出循环，value是循环的标签，没有标签就是最里面的循环
+	aStatementBreak
+	//aStatementContinue 进入循环的下一轮，for 会先跑 step，value同上
+	aStatementContinue
+	//aStatementSwitch params里是被比较的值，body里是 aSwitchCase
+	aStatementSwitch
+	//aSwitchCase 一个 case 或 default，params是和它比较的值，body是它的语句
+	aSwitchCase
+	//aCompoundIndexAssignment 复合赋值 a[i] += v，params是下标表达式和右边的值，
+	//token是运算符。列表和下标只算一次
+	aCompoundIndexAssignment
+)
+
+/*
+Parser keeps all the state of one parse, so that many of them can run at the
+same time and every parse starts from a clean state.
+*/
+type Parser struct {
+	/*This is the counter variable that we'll use for parsing.*/
+	pc int
+	/*This variable will store our slice of `token`s inside of it.*/
+	pt []Token
+	/*How many `(` we are in, new lines do not end anything inside of them.*/
+	depth int
+	/*How many function bodies we are in, `return` is only allowed inside.*/
+	functions int
+	/*The labels of the loops we are in, "" for a loop without one. `break`
+	and `continue` are only allowed inside, a function starts without loops.*/
+	loops []string
+	/*How many switch bodies we are in, a `break` without a label ends the
+	innermost one.*/
+	switches int
+	/*Whether the statements are the ones of a case, `case` and `default` end
+	them as well.*/
+	inCase bool
+	/*Every error we ran into so far, the parse goes on after them.*/
+	errors ErrorList
+}
+
+// SyntaxError is an error of the tokenizer or the parser at Line and Col.
+// Found is the token it choked on, of kind 0 at the end of the tokens, and
+// Expected is the set of things that could have been there instead. The
+// errors that are not about an unexpected token have a Message and no
+// Expected.
+type SyntaxError struct {
+	// Code tells the kind of the error, like parser/unexpected-token
+	Code     string
+	Line     int
+	Col      int
+	Found    Token
+	Expected []string
+	Message  string
+	// Notes tell more about the error, like where the `(` that is not
+	// closed was opened
+	Notes []string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("%s at line %d, col %d", e.reason(), e.Line, e.Col)
+}
+
+// reason is the error without its position
+func (e *SyntaxError) reason() string {
+	if e.Message != "" {
+		return e.Message
+	}
+	should := strings.Join(e.Expected, " or ")
+	switch e.Found.kind {
+	case 0:
+		return fmt.Sprintf("unexpected end of tokens, should be %s", should)
+	case tNewLine:
+		return fmt.Sprintf("unexpected new line, should be %s", should)
+	}
+	return fmt.Sprintf("unexpected token `%s`, should be %s", tokenText(e.Found), should)
+}
+
+// syntaxError builds the SyntaxError of something wrong with the token t
+func syntaxError(t Token, code string, format string, args ...interface{}) *SyntaxError {
+	return &SyntaxError{Code: code, Line: t.line, Col: t.col, Found: t, Message: fmt.Sprintf(format, args...)}
+}
+
+// ErrorList is every error of a parse, in the order of the source
+type ErrorList []error
+
+func (l ErrorList) Error() string {
+	messages := make([]string, len(l))
+	for i, err := range l {
+		messages[i] = err.Error()
+	}
+	return strings.Join(messages, "\n")
+}
+
+// Unwrap lets errors.Is and errors.As look at every error of the list
+func (l ErrorList) Unwrap() []error {
+	return l
+}
+
+// JoinErrors puts the errors of the tokenizer and of the parser into one
+// ErrorList in the order of the source, it is nil if there are none
+func JoinErrors(errs ...error) error {
+	var joined ErrorList
+	for _, err := range errs {
+		var list ErrorList
+		switch {
+		case err == nil:
+		case errors.As(err, &list):
+			joined = append(joined, list...)
+		default:
+			joined = append(joined, err)
+		}
+	}
+	if len(joined) == 0 {
+		return nil
+	}
+	sort.SliceStable(joined, func(i, j int) bool {
+		iLine, iCol := errorPosition(joined[i])
+		jLine, jCol := errorPosition(joined[j])
+		return iLine < jLine || iLine == jLine && iCol < jCol
+	})
+	return joined
+}
+
+// errorPosition is the line and col of a SyntaxError, 0 for the others
+func errorPosition(err error) (int, int) {
+	var syntaxErr *SyntaxError
+	if errors.As(err, &syntaxErr) {
+		return syntaxErr.Line, syntaxErr.Col
+	}
+	return 0, 0
+}
+
+// newParser creates a Parser working on the tokens
+func newParser(tokens []Token) *Parser {
+	return &Parser{
+		pt: tokens,
+	}
+}
+
+/*
+Every binary operator gets a precedence, the higher it is the tighter the
+operator binds its operands, and an associativity that decides which way a
+chain of operators of the same precedence leans:
+
+	a - b - c   =>  (a - b) - c    left associative
+	a = b = c   =>  a = (b = c)    right associative
+*/
+type operator struct {
+	precedence int
+	rightAssoc bool
+}
+
+// infixOperators is the table of all the binary operators
+// Priority:
+// 1. () ! - +        in operand()
+// 2. * / %
+// 3. + -
+// 4. > >= < <= == !=
+// 5. &&
+// 6. ||
+// 7. = += -= *= /= %=
+var infixOperators = map[int]operator{
+	tEqual:            {1, true},
+	tPlusEqual:        {1, true},
+	tMinusEqual:       {1, true},
+	tMultiplyEqual:    {1, true},
+	tDivideEqual:      {1, true},
+	tModuloEqual:      {1, true},
+	tOr:               {2, false},
+	tAnd:              {3, false},
+	tCalcNotEqual:     {4, false},
+	tCalcLessThan:     {4, false},
+	tCalcLessEqual:    {4, false},
+	tCalcGreaterThan:  {4, false},
+	tCalcGreaterEqual: {4, false},
+	tCalcEqual:        {4, false},
+	tPlus:             {5, false},
+	tMinus:            {5, false},
+	tMultiply:         {6, false},
+	tDivide:           {6, false},
+	tModulo:           {6, false},
+}
+
+// compoundAssignments map[assignment]operator, `a += b` is `a = a + b` and
+// `a++` is `a += 1`
+var compoundAssignments = map[int]int{
+	tPlusEqual:     tPlus,
+	tMinusEqual:    tMinus,
+	tMultiplyEqual: tMultiply,
+	tDivideEqual:   tDivide,
+	tModuloEqual:   tModulo,
+	tIncrement:     tPlus,
+	tDecrement:     tMinus,
+}
+
+// prefixOperators are the operators in front of an operand, they bind
+// tighter than all the binary ones
+var prefixOperators = map[int]bool{
+	tNot:   true,
+	tMinus: true,
+	tPlus:  true,
+}
+
+/*Okay, so we define a `Parse` function that accepts our slice of `tokens`.
+The comments are dropped first, the parser never sees them.*/
+/*
+var astNode = ast{
+	kind: aProgram,
+	body: []node{
+		{
+			kind: aExpression,
+			name: "+",
+			params: []node{
+				{
+					kind:  aNumberLiteral,
+					value: "2",
+				},{
+					kind:  aNumberLiteral,
+					value: "4",
+				},
+			},
+		},
+	},
+}*/
+func Parse(tokens []Token) (Node, error) {
+	/*Every call gets its own parser, with a fresh counter.*/
+	return newParser(stripTrivia(tokens)).parse()
+}
+
+// parse the tokens of the Parser into a `Program` node
+func (p *Parser) parse() (Node, error) {
+	/*Now, we're going to create our AST which will have a root which is a
+	`Program` node.*/
+	astRoot := Node{
+		Kind: aProgram,
+		Body: []Node{},
+	}
+	/*And we're going to kickstart our `walk` function, which you can find just
+	below this, we'll be pushing nodes to our `ast.body` slice.
+
+	The reason we are doing this inside a loop is because our program can have
+	statements after one another instead of being nested.
+
+	  a = 100 + 200
+	  print(a)
+	*/
+	for {
+		p.skipSeparators()
+		if p.peek().kind == 0 {
+			break
+		}
+		start := p.pc
+		astBodyNode, err := p.walk()
+		if err != nil {
+			/*A broken statement is left out of the AST, we carry on with the
+			next one so that all the errors are found in one go.*/
+			p.fail(err, start)
+			p.functions = 0
+			p.loops = nil
+			p.switches = 0
+			p.inCase = false
+			// a `}` right after a broken statement most likely closes a block
+			// whose `{` went missing, like `while (a) print(a) }`
+			if p.peek().kind == tRBrace && p.pt[start].kind != tRBrace {
+				p.next()
+			}
+			continue
+		}
+		astRoot.Body = append(astRoot.Body, astBodyNode)
+	}
+
+	/*At the end of our parser we'll return the AST, only the statements that
+	parsed are in there when there are errors.*/
+	if len(p.errors) > 0 {
+		return astRoot, p.errors
+	}
+	return astRoot, nil
+}
+
+// peek at the current token without taking it, a token of kind 0 means
+// that we are at the end
+func (p *Parser) peek() Token {
+	// new lines mean nothing inside of `()`
+	for p.depth > 0 && p.pc < len(p.pt) && p.pt[p.pc].kind == tNewLine {
+		p.pc++
+	}
+	if p.pc >= len(p.pt) {
+		if len(p.pt) == 0 {
+			return Token{line: 1, col: 1}
+		}
+		last := p.pt[len(p.pt)-1]
+		return Token{line: last.line, col: last.col + len(last.value)}
+	}
+	return p.pt[p.pc]
+}
+
+// next takes the current token
+func (p *Parser) next() Token {
+	t := p.peek()
+	if t.kind != 0 {
+		p.pc++
+	}
+	return t
+}
+
+// expect takes the current token if it is of the kind, expected is used in
+// the error message
+func (p *Parser) expect(kind int, expected ...string) (Token, error) {
+	t := p.peek()
+	if t.kind != kind {
+		return t, unexpected(t, expected...)
+	}
+	return p.next(), nil
+}
+
+// expectClosing is expect for the token closing the bracket open, the error
+// tells where open is
+func (p *Parser) expectClosing(kind int, open Token, expected ...string) (Token, error) {
+	t, err := p.expect(kind, expected...)
+	if err != nil {
+		return t, unclosedBracket(err, open)
+	}
+	return t, nil
+}
+
+// unclosedBracket adds the note about the bracket open to err
+func unclosedBracket(err error, open Token) error {
+	closer := map[int]string{tLParen: ")", tLBrace: "}", tLBracket: "]"}[open.kind]
+	if e, ok := err.(*SyntaxError); ok {
+		e.Notes = append(e.Notes, fmt.Sprintf("expected `%s` to close `%s` opened at %d:%d", closer, open.value, open.line, open.col))
+	}
+	return err
+}
+
+// skipNewLines skips the new lines in front of us
+func (p *Parser) skipNewLines() {
+	for p.pc < len(p.pt) && p.pt[p.pc].kind == tNewLine {
+		p.pc++
+	}
+}
+
+// skipSeparators skips the new lines and `;` between statements
+func (p *Parser) skipSeparators() {
+	for p.pc < len(p.pt) && (p.pt[p.pc].kind == tNewLine || p.pt[p.pc].kind == tBreak) {
+		p.pc++
+	}
+}
+
+// fail keeps err and skips the rest of the broken statement that started at
+// the token start, so the parse can go on with the next one
+func (p *Parser) fail(err error, start int) {
+	// a missing `}` at the end is reported by every block it is missing from
+	if n := len(p.errors); n == 0 || p.errors[n-1].Error() != err.Error() {
+		p.errors = append(p.errors, err)
+	}
+	p.depth = 0
+	p.synchronize()
+	if p.pc == start {
+		// the statement did not take a single token, skip the one it choked on
+		p.next()
+	}
+}
+
+// synchronize skips tokens up to a place where a statement can start: after
+// a `;` or a new line, or before a `}` or a keyword starting a statement. A
+// block it runs into is skipped as a whole, up to its own `}`.
+func (p *Parser) synchronize() {
+	blocks := 0
+	for {
+		t := p.peek()
+		switch {
+		case t.kind == 0:
+			return
+		case t.kind == tLBrace:
+			blocks++
+		case t.kind == tRBrace && blocks > 0:
+			blocks--
+		case blocks > 0:
+		case t.kind == tRBrace, t.kind == tIf, t.kind == tFor, t.kind == tWhile, t.kind == tReturn,
+			t.kind == tBreakLoop, t.kind == tContinue, t.kind == tSwitch, t.kind == tCase, t.kind == tDefault:
+			return
+		case t.kind == tNewLine, t.kind == tBreak:
+			p.next()
+			return
+		}
+		p.next()
+	}
+}
+
+// unexpected builds the error for a token we did not want here
+func unexpected(t Token, expected ...string) error {
+	return &SyntaxError{Code: codeUnexpectedToken, Line: t.line, Col: t.col, Found: t, Expected: expected}
+}
+
+/*
+But this time we're going to use recursion instead of a `while` loop. So we
+define a `walk` function, that walks through one statement and generates its
+ast.
+*/
+// a = 1 + 2
+// a = 1 + 2 * 3
+// print(a)
+// int f(int a, int b) { return a + b; }
+// f(1, 2)
+// int main(){}
+func (p *Parser) walk() (Node, error) {
+	/*Inside the walk function we start by grabbing the `current` token.*/
+	currentToken := p.peek()
+
+	switch currentToken.kind {
+	case tLBrace:
+		return p.walkBlock()
+	case tIf:
+		return p.walkIf()
+	case tFor:
+		return p.walkFor("")
+	case tWhile:
+		return p.walkWhile("")
+	case tSwitch:
+		return p.walkSwitch()
+	case tReturn:
+		return p.walkReturn()
+	case tBreakLoop, tContinue:
+		return p.walkJump()
+	case tIdentifier:
+		// outer: for (...) {}
+		if p.pc+1 < len(p.pt) && p.pt[p.pc+1].kind == tColon {
+			return p.walkLabel()
+		}
+		// int f(int a, int b) {}
+		if p.pc+2 < len(p.pt) && p.pt[p.pc+1].kind == tIdentifier && p.pt[p.pc+2].kind == tLParen {
+			return p.walkFunction()
+		}
+	}
+
+	/*Everything else is an expression, it has to be the last thing on its
+	line.*/
+	currentNode, err := p.simpleStatement()
+	if err != nil {
+		return Node{}, err
+	}
+	if !p.atEndOfStatement() {
+		return Node{}, unexpected(p.peek(), "the end of the statement")
+	}
+	return currentNode, nil
+}
+
+// atEndOfStatement tells whether the current token ends a statement, the
+// next case ends the statements of a case like in
+// `case 1: print(1) default: print(2)`
+func (p *Parser) atEndOfStatement() bool {
+	switch p.peek().kind {
+	case 0, tNewLine, tBreak, tRBrace:
+		return true
+	case tCase, tDefault:
+		return p.inCase
+	}
+	return false
+}
+
+// {}
+// tLBrace -> aStatement{body={statements...}}
+func (p *Parser) walkBlock() (Node, error) {
+	currentToken, err := p.expect(tLBrace, "`{`")
+	if err != nil {
+		return Node{}, err
+	}
+	currentNode := Node{
+		Kind:  aStatement,
+		Name:  currentToken.value,
+		token: currentToken,
+		Body:  []Node{},
+	}
+	// new lines are statement separators again, even inside of a `()`, and
+	// a case does not end the statements of a block inside of it
+	depth, inCase := p.depth, p.inCase
+	p.depth, p.inCase = 0, false
+	for {
+		p.skipSeparators()
+		t := p.peek()
+		if t.kind == tRBrace {
+			break
+		}
+		if t.kind == 0 {
+			p.inCase = inCase
+			return Node{}, unclosedBracket(unexpected(t, "`}`"), currentToken)
+		}
+		start := p.pc
+		tempNode, err := p.walk()
+		if err != nil {
+			p.fail(err, start)
+			continue
+		}
+		currentNode.Body = append(currentNode.Body, tempNode)
+	}
+	p.depth, p.inCase = depth, inCase
+	// skip the closing brace
+	p.next()
+	return currentNode, nil
+}
+
+// condition parses the `(Expression)` after `if` and `while`
+func (p *Parser) condition() (Node, error) {
+	open, err := p.expect(tLParen, "`(`")
+	if err != nil {
+		return Node{}, err
+	}
+	p.depth++
+	currentNode, err := p.expression(1)
+	if err != nil {
+		return Node{}, err
+	}
+	p.depth--
+	if _, err = p.expectClosing(tRParen, open, "`)`"); err != nil {
+		return Node{}, err
+	}
+	return currentNode, nil
+}
+
+// tIf -> aStatementIf{param=(aExpression); body={aStatement, [aStatement | aStatementIf]}}
+// `else if` is an if in the place of the else block
+func (p *Parser) walkIf() (Node, error) {
+	currentToken := p.next()
+	currentNode := Node{
+		Kind:  aStatementIf,
+		Name:  currentToken.value,
+		token: currentToken,
+	}
+
+	// if param
+	ifExpression, err := p.condition()
+	if err != nil {
+		return Node{}, err
+	}
+	currentNode.Params = []Node{ifExpression}
+
+	// if body
+	ifTrue, err := p.walkBlock()
+	if err != nil {
+		return Node{}, err
+	}
+	currentNode.Body = []Node{ifTrue}
+
+	// else? append to body
+	pc := p.pc
+	p.skipNewLines()
+	if p.peek().kind != tElse {
+		p.pc = pc
+		return currentNode, nil
+	}
+	p.next()
+	walkElse := p.walkBlock
+	if p.peek().kind == tIf {
+		walkElse = p.walkIf
+	}
+	ifFalseElse, err := walkElse()
+	if err != nil {
+		return Node{}, err
+	}
+	currentNode.Body = append(currentNode.Body, ifFalseElse)
+	return currentNode, nil
+}
+
+// tSwitch -> aStatementSwitch{param=(aExpression); body={aSwitchCase...}}
+func (p *Parser) walkSwitch() (Node, error) {
+	currentToken := p.next()
+	currentNode := Node{
+		Kind:  aStatementSwitch,
+		Name:  currentToken.value,
+		token: currentToken,
+		Body:  []Node{},
+	}
+
+	// switch param
+	switchExpression, err := p.condition()
+	if err != nil {
+		return Node{}, err
+	}
+	currentNode.Params = []Node{switchExpression}
+
+	// switch body, the statements go into the case in front of them
+	open, err := p.expect(tLBrace, "`{`")
+	if err != nil {
+		return Node{}, err
+	}
+	depth, inCase := p.depth, p.inCase
+	p.depth, p.inCase = 0, true
+	p.switches++
+	var defaultCase *Token
+	// the statements after a case that did not parse are checked and left out
+	broken := false
+	for {
+		p.skipSeparators()
+		t := p.peek()
+		if t.kind == tRBrace {
+			break
+		}
+		if t.kind == 0 {
+			p.inCase = inCase
+			p.switches--
+			return Node{}, unclosedBracket(unexpected(t, "`case`", "`default`", "`}`"), open)
+		}
+		start := p.pc
+		if t.kind == tCase || t.kind == tDefault {
+			caseNode, err := p.walkCase()
+			if err == nil && t.kind == tDefault && defaultCase != nil {
+				duplicate := syntaxError(t, codeDuplicateDefault, "more than one default in a switch")
+				duplicate.Notes = append(duplicate.Notes, fmt.Sprintf("the first default is at %d:%d", defaultCase.line, defaultCase.col))
+				err = duplicate
+			}
+			broken = err != nil
+			if err != nil {
+				p.fail(err, start)
+				continue
+			}
+			if t.kind == tDefault {
+				defaultCase = &t
+			}
+			currentNode.Body = append(currentNode.Body, caseNode)
+			continue
+		}
+		if len(currentNode.Body) == 0 && !broken {
+			p.fail(unexpected(t, "`case`", "`default`", "`}`"), start)
+			continue
+		}
+		tempNode, err := p.walk()
+		if err != nil {
+			p.fail(err, start)
+			continue
+		}
+		if broken {
+			continue
+		}
+		last := &currentNode.Body[len(currentNode.Body)-1]
+		last.Body = append(last.Body, tempNode)
+	}
+	p.depth, p.inCase = depth, inCase
+	p.switches--
+	// skip the closing brace
+	p.next()
+	return currentNode, nil
+}
+
+// tCase -> aSwitchCase{params={aExpression...}; body={aStatement...}}
+// tDefault -> aSwitchCase{params={}; body={aStatement...}}
+// the statements of the case are added by walkSwitch
+func (p *Parser) walkCase() (Node, error) {
+	currentToken := p.next()
+	currentNode := Node{
+		Kind:   aSwitchCase,
+		Name:   currentToken.value,
+		token:  currentToken,
+		Params: []Node{},
+		Body:   []Node{},
+	}
+	for currentToken.kind == tCase {
+		caseValue, err := p.expression(1)
+		if err != nil {
+			return Node{}, err
+		}
+		currentNode.Params = append(currentNode.Params, caseValue)
+		if p.peek().kind != tComma {
+			break
+		}
+		p.next()
+	}
+	expected := []string{"`:`"}
+	if currentToken.kind == tCase {
+		expected = append(expected, "`,`")
+	}
+	if _, err := p.expect(tColon, expected...); err != nil {
+		return Node{}, err
+	}
+	return currentNode, nil
+}
+
+// tIdentifier tColon -> the loop after it, with the label in its value
+func (p *Parser) walkLabel() (Node, error) {
+	label := p.next()
+	p.next()
+	p.skipNewLines()
+	switch p.peek().kind {
+	case tFor:
+		return p.walkFor(label.value)
+	case tWhile:
+		return p.walkWhile(label.value)
+	}
+	return Node{}, unexpected(p.peek(), "`for`", "`while`")
+}
+
+// loopBody parses the block of a loop, `break` and `continue` inside of it
+// belong to the loop labeled label
+func (p *Parser) loopBody(label string) (Node, error) {
+	p.loops = append(p.loops, label)
+	body, err := p.walkBlock()
+	p.loops = p.loops[:len(p.loops)-1]
+	return body, err
+}
+
+// tWhile -> aStatementWhile{value=label; param=(aExpression); body={aStatement}}
+func (p *Parser) walkWhile(label string) (Node, error) {
+	currentToken := p.next()
+	currentNode := Node{
+		Kind:  aStatementWhile,
+		Name:  currentToken.value,
+		Value: label,
+		token: currentToken,
+	}
+
+	// while param
+	whileExpression, err := p.condition()
+	if err != nil {
+		return Node{}, err
+	}
+	currentNode.Params = []Node{whileExpression}
+
+	// while body
+	whileBody, err := p.loopBody(label)
+	if err != nil {
+		return Node{}, err
+	}
+	currentNode.Body = []Node{whileBody}
+	return currentNode, nil
+}
+
+// tFor -> aStatementFor{value=label; param=(init ; condition ; step); body={aStatement}}
+// the two `;` are kept as aBlank, so the params are always 5 and the parts
+// that are left out are aBlank as well
+func (p *Parser) walkFor(label string) (Node, error) {
+	currentToken := p.next()
+	currentNode := Node{
+		Kind:  aStatementFor,
+		Name:  currentToken.value,
+		Value: label,
+		token: currentToken,
+	}
+
+	// for param
+	open, err := p.expect(tLParen, "`(`")
+	if err != nil {
+		return Node{}, err
+	}
+	p.depth++
+	for _, end := range []int{tBreak, tBreak, tRParen} {
+		t := p.peek()
+		if t.kind == end {
+			currentNode.Params = append(currentNode.Params, Node{Kind: aBlank, token: t})
+		} else {
+			// the init and the step are statements, the condition is not
+			parse := p.simpleStatement
+			if len(currentNode.Params) == 2 {
+				parse = func() (Node, error) { return p.expression(1) }
+			}
+			forExpression, err := parse()
+			if err != nil {
+				return Node{}, err
+			}
+			currentNode.Params = append(currentNode.Params, forExpression)
+		}
+		if end == tRParen {
+			p.depth--
+			if _, err := p.expectClosing(tRParen, open, "`)`"); err != nil {
+				return Node{}, err
+			}
+			break
+		}
+		t, err := p.expect(tBreak, "`;`")
+		if err != nil {
+			return Node{}, err
+		}
+		currentNode.Params = append(currentNode.Params, Node{Kind: aBlank, Name: t.value, token: t})
+	}
+
+	// for body
+	forBody, err := p.loopBody(label)
+	if err != nil {
+		return Node{}, err
+	}
+	currentNode.Body = []Node{forBody}
+	return currentNode, nil
+}
+
+// int f(int a, int b) { return a + b; }
+// tIdentifier tIdentifier -> aFunction{value=type; params={aExpression...}; body={aStatement}}
+func (p *Parser) walkFunction() (Node, error) {
+	returnType := p.next()
+	currentToken := p.next()
+	if lookupBuiltin(currentToken.value) != nil {
+		return Node{}, syntaxError(currentToken, codeBuiltinRedeclared, "%s is a builtin function", currentToken.value)
+	}
+	currentNode := Node{
+		Kind:   aFunction,
+		Name:   currentToken.value,
+		Value:  returnType.value,
+		token:  currentToken,
+		Params: []Node{},
+	}
+
+	// function params
+	open := p.next()
+	p.depth++
+	for p.peek().kind != tRParen {
+		if len(currentNode.Params) > 0 {
+			if _, err := p.expectClosing(tComma, open, "`,`", "`)`"); err != nil {
+				return Node{}, err
+			}
+		}
+		paramType, err := p.expect(tIdentifier, "the type of a param")
+		if err != nil {
+			return Node{}, err
+		}
+		paramName, err := p.expect(tIdentifier, "the name of a param")
+		if err != nil {
+			return Node{}, err
+		}
+		for _, param := range currentNode.Params {
+			if param.Name == paramName.value {
+				return Node{}, syntaxError(paramName, codeDuplicateParam, "duplicate param %s", paramName.value)
+			}
+		}
+		currentNode.Params = append(currentNode.Params, Node{
+			Kind:  aExpression,
+			Name:  paramName.value,
+			Value: paramType.value,
+			token: paramName,
+		})
+	}
+	p.depth--
+	p.next()
+
+	// function body, the loops and switches around the declaration are not
+	// ours
+	p.functions++
+	loops, switches := p.loops, p.switches
+	p.loops, p.switches = nil, 0
+	functionBody, err := p.walkBlock()
+	p.loops, p.switches = loops, switches
+	if err != nil {
+		return Node{}, err
+	}
+	p.functions--
+	currentNode.Body = []Node{functionBody}
+	return currentNode, nil
+}
+
+// tReturn -> aStatementReturn{params={[aExpression]}}
+func (p *Parser) walkReturn() (Node, error) {
+	currentToken := p.next()
+	if p.functions == 0 {
+		return Node{}, syntaxError(currentToken, codeReturnOutsideFunction, "return outside of a function")
+	}
+	currentNode := Node{
+		Kind:   aStatementReturn,
+		Name:   currentToken.value,
+		token:  currentToken,
+		Params: []Node{},
+	}
+	if p.atEndOfStatement() {
+		return currentNode, nil
+	}
+	returnValue, err := p.expression(1)
+	if err != nil {
+		return Node{}, err
+	}
+	currentNode.Params = append(currentNode.Params, returnValue)
+	if !p.atEndOfStatement() {
+		return Node{}, unexpected(p.peek(), "the end of the statement")
+	}
+	return currentNode, nil
+}
+
+// tBreakLoop -> aStatementBreak{value=label}
+// tContinue -> aStatementContinue{value=label}
+func (p *Parser) walkJump() (Node, error) {
+	currentToken := p.next()
+	currentNode := Node{
+		Kind:  aStatementBreak,
+		Name:  currentToken.value,
+		token: currentToken,
+	}
+	code := codeBreakOutsideLoop
+	if currentToken.kind == tContinue {
+		currentNode.Kind = aStatementContinue
+		code = codeContinueOutsideLoop
+	}
+	if len(p.loops) == 0 && currentNode.Kind == aStatementContinue {
+		return Node{}, syntaxError(currentToken, code, "continue outside of a loop")
+	}
+	// a `break` without a label can end a switch as well
+	if len(p.loops) == 0 && p.switches == 0 {
+		return Node{}, syntaxError(currentToken, code, "break outside of a loop or a switch")
+	}
+	if p.peek().kind == tIdentifier {
+		label := p.next()
+		found := false
+		for _, l := range p.loops {
+			found = found || l == label.value
+		}
+		if !found {
+			return Node{}, syntaxError(label, codeUndefinedLabel, "no loop labeled %s around this %s", label.value, currentToken.value)
+		}
+		currentNode.Value = label.value
+	}
+	if !p.atEndOfStatement() {
+		return Node{}, unexpected(p.peek(), "a label", "the end of the statement")
+	}
+	return currentNode, nil
+}
+
+/*
+expression is the heart of the parser. It parses an operand, and then keeps
+taking the operators that bind at least as tight as minPrecedence, every
+right hand side is parsed by calling itself with a higher minPrecedence:
+
+	1 + 2 * 3 + 4
+
+	operand 1, `+` has precedence 5
+	  expression(6): operand 2, `*` has precedence 6
+	    expression(7): operand 3, `+` is too weak, give back 3
+	  give back (2 * 3), `+` is too weak
+	(1 + (2 * 3)), `+` has precedence 5
+	  expression(6): operand 4
+	((1 + (2 * 3)) + 4)
+*/
+func (p *Parser) expression(minPrecedence int) (Node, error) {
+	left, err := p.operand()
+	if err != nil {
+		return Node{}, err
+	}
+	for {
+		currentToken := p.peek()
+		op, ok := infixOperators[currentToken.kind]
+		if !ok || op.precedence < minPrecedence {
+			return left, nil
+		}
+		p.next()
+		// an operator at the end of a line carries on to the next one
+		p.skipNewLines()
+
+		nextPrecedence := op.precedence + 1
+		if op.rightAssoc {
+			nextPrecedence = op.precedence
+		}
+		right, err := p.expression(nextPrecedence)
+		if err != nil {
+			return Node{}, err
+		}
+
+		if _, compound := compoundAssignments[currentToken.kind]; compound || currentToken.kind == tEqual {
+			if left, err = assignment(currentToken, left, right); err != nil {
+				return Node{}, err
+			}
+			continue
+		}
+		left = Node{
+			Kind:   aExpression,
+			Name:   currentToken.value,
+			token:  currentToken,
+			Params: []Node{left, right},
+		}
+	}
+}
+
+// assignment builds the aAssignmentStatement of `target = value`, a compound
+// assignment like `target += value` assigns `target + value`. The target is a
+// variable or an item of a list like `a[i]`, a compound assignment to an item
+// is an aCompoundIndexAssignment so the list and the index are worked out once.
+func assignment(t Token, target Node, value Node) (Node, error) {
+	variable := target.Kind == aExpression && target.token.kind == tIdentifier && target.Params == nil
+	if !variable && target.Kind != aIndexExpression {
+		return Node{}, syntaxError(t, codeInvalidAssignment, "trying to assign to a non-id target")
+	}
+	if op, ok := compoundAssignments[t.kind]; ok {
+		operator := Token{op, t.value[:1], t.line, t.col}
+		if !variable {
+			return Node{
+				Kind:   aCompoundIndexAssignment,
+				Name:   t.value,
+				token:  operator,
+				Params: []Node{target, value},
+			}, nil
+		}
+		value = Node{
+			Kind:   aExpression,
+			Name:   operator.value,
+			token:  operator,
+			Params: []Node{target, value},
+		}
+	}
+	return Node{
+		Kind:   aAssignmentStatement,
+		Name:   t.value,
+		token:  t,
+		Params: []Node{target, value},
+	}, nil
+}
+
+// simpleStatement parses an expression that is a statement of its own, like
+// the init and the step of a for, where `i++` and `i--` are allowed too
+func (p *Parser) simpleStatement() (Node, error) {
+	currentNode, err := p.expression(1)
+	if err != nil {
+		return Node{}, err
+	}
+	t := p.peek()
+	if t.kind != tIncrement && t.kind != tDecrement {
+		return currentNode, nil
+	}
+	p.next()
+	one := Node{Kind: aNumberLiteral, Name: "1", Value: "1", token: t}
+	return assignment(t, currentNode, one)
+}
+
+// operand parses the things that operators work on: a primary with the
+// indexes after it like `a[i][j]`, or an operand behind a prefix operator
+func (p *Parser) operand() (Node, error) {
+	currentToken := p.peek()
+	if prefixOperators[currentToken.kind] {
+		p.next()
+		inner, err := p.operand()
+		if err != nil {
+			return Node{}, err
+		}
+		return Node{
+			Kind:   aExpression,
+			Name:   currentToken.value,
+			token:  currentToken,
+			Params: []Node{inner},
+		}, nil
+	}
+	currentNode, err := p.primary()
+	if err != nil {
+		return Node{}, err
+	}
+	for p.peek().kind == tLBracket {
+		open := p.next()
+		p.depth++
+		// the errors about the index are at its first token
+		start := p.peek()
+		index, err := p.expression(1)
+		if err != nil {
+			return Node{}, err
+		}
+		p.depth--
+		if _, err = p.expectClosing(tRBracket, open, "`]`"); err != nil {
+			return Node{}, err
+		}
+		currentNode = Node{
+			Kind:   aIndexExpression,
+			Name:   open.value,
+			token:  start,
+			Params: []Node{currentNode, index},
+		}
+	}
+	return currentNode, nil
+}
+
+// primary parses literals, identifiers, function calls, `(Expression)`,
+// `[List]` and `{Map}`
+func (p *Parser) primary() (Node, error) {
+	currentToken := p.peek()
+	switch currentToken.kind {
+	case tInteger:
+		p.next()
+		if _, err := strconv.Atoi(currentToken.value); err != nil {
+			return Node{}, syntaxError(currentToken, codeInvalidInt, "invalid int %s", currentToken.value)
+		}
+		return Node{
+			Kind:  aNumberLiteral,
+			Name:  currentToken.value,
+			Value: currentToken.value,
+			token: currentToken,
+		}, nil
+
+	case tFloat:
+		p.next()
+		if _, err := strconv.ParseFloat(currentToken.value, 64); err != nil {
+			return Node{}, syntaxError(currentToken, codeInvalidFloat, "invalid float %s", currentToken.value)
+		}
+		return Node{
+			Kind:  aFloatLiteral,
+			Name:  currentToken.value,
+			Value: currentToken.value,
+			token: currentToken,
+		}, nil
+
+	case tString:
+		p.next()
+		return Node{
+			Kind:  aStringLiteral,
+			Name:  currentToken.value,
+			Value: decodeString(currentToken.value),
+			token: currentToken,
+		}, nil
+
+	// ()
+	case tLParen:
+		p.next()
+		p.depth++
+		inner, err := p.expression(1)
+		if err != nil {
+			return Node{}, err
+		}
+		p.depth--
+		if _, err = p.expectClosing(tRParen, currentToken, "`)`"); err != nil {
+			return Node{}, err
+		}
+		return Node{
+			Kind:   aExpression,
+			Name:   currentToken.value,
+			token:  currentToken,
+			Params: []Node{inner},
+		}, nil
+
+	// [1, 2, 3]
+	case tLBracket:
+		p.next()
+		p.depth++
+		currentNode := Node{
+			Kind:   aListLiteral,
+			Name:   currentToken.value,
+			token:  currentToken,
+			Params: []Node{},
+		}
+		for p.peek().kind != tRBracket {
+			if len(currentNode.Params) > 0 {
+				if _, err := p.expectClosing(tComma, currentToken, "`,`", "`]`"); err != nil {
+					return Node{}, err
+				}
+				// a `,` after the last item is fine
+				if p.peek().kind == tRBracket {
+					break
+				}
+			}
+			item, err := p.expression(1)
+			if err != nil {
+				return Node{}, err
+			}
+			currentNode.Params = append(currentNode.Params, item)
+		}
+		p.depth--
+		p.next()
+		return currentNode, nil
+
+	// {"k": 1, "v": 2}, where an expression goes `{` is a map and not a block
+	case tLBrace:
+		p.next()
+		p.depth++
+		currentNode := Node{
+			Kind:   aMapLiteral,
+			Name:   currentToken.value,
+			token:  currentToken,
+			Params: []Node{},
+		}
+		for p.peek().kind != tRBrace {
+			if len(currentNode.Params) > 0 {
+				if _, err := p.expectClosing(tComma, currentToken, "`,`", "`}`"); err != nil {
+					return Node{}, err
+				}
+				// a `,` after the last entry is fine
+				if p.peek().kind == tRBrace {
+					break
+				}
+			}
+			key, err := p.expression(1)
+			if err != nil {
+				return Node{}, err
+			}
+			if _, err := p.expect(tColon, "`:`"); err != nil {
+				return Node{}, err
+			}
+			value, err := p.expression(1)
+			if err != nil {
+				return Node{}, err
+			}
+			currentNode.Params = append(currentNode.Params, key, value)
+		}
+		p.depth--
+		p.next()
+		return currentNode, nil
+
+	// tIdentifier Function Call
+	case tIdentifier, tPrint:
+		p.next()
+		if currentToken.kind == tPrint && p.peek().kind != tLParen {
+			// print is a builtin, it can only be called
+			return Node{}, unexpected(p.peek(), "`(` after print")
+		}
+		switch currentToken.value {
+		case "true", "false":
+			return Node{
+				Kind:  aBoolLiteral,
+				Name:  currentToken.value,
+				Value: currentToken.value,
+				token: currentToken,
+			}, nil
+		case "nil":
+			return Node{
+				Kind:  aNilLiteral,
+				Name:  currentToken.value,
+				Value: currentToken.value,
+				token: currentToken,
+			}, nil
+		}
+		currentNode := Node{
+			Kind:  aExpression,
+			Name:  currentToken.value,
+			token: currentToken,
+		}
+		if p.peek().kind != tLParen {
+			// looks like someone is calling us like `1 + a`
+			return currentNode, nil
+		}
+		// looks like it is Calling a function like `a(1, 2)`
+		currentNode.Params = []Node{}
+		open := p.next()
+		p.depth++
+		for p.peek().kind != tRParen {
+			if len(currentNode.Params) > 0 {
+				if _, err := p.expectClosing(tComma, open, "`,`", "`)`"); err != nil {
+					return Node{}, err
+				}
+			}
+			param, err := p.expression(1)
+			if err != nil {
+				return Node{}, err
+			}
+			currentNode.Params = append(currentNode.Params, param)
+		}
+		p.depth--
+		p.next()
+		return currentNode, nil
+	}
+
+	return Node{}, unexpected(currentToken, "an expression")
+}
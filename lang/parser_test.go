@@ -0,0 +1,460 @@
+package lang
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// sexpr prints a Node tree as an s-expression, `(` groups are printed as
+// `(paren x)` and blocks as `{x y}`
+func sexpr(n Node) string {
+	switch n.Kind {
+	case aNumberLiteral, aFloatLiteral, aStringLiteral, aBoolLiteral, aNilLiteral:
+		return n.Value
+	case aBlank:
+		if n.Name == "" {
+			return "_"
+		}
+		return n.Name
+	case aProgram, aStatement:
+		parts := make([]string, len(n.Body))
+		for i, b := range n.Body {
+			parts[i] = sexpr(b)
+		}
+		if n.Kind == aProgram {
+			return strings.Join(parts, "; ")
+		}
+		return "{" + strings.Join(parts, " ") + "}"
+	}
+	name := n.Name
+	if name == "(" {
+		name = "paren"
+	}
+	// the labels of the loops and of break and continue
+	switch n.Kind {
+	case aStatementWhile, aStatementFor, aStatementBreak, aStatementContinue:
+		if n.Value != "" {
+			name += ":" + n.Value
+		}
+	}
+	if len(n.Params) == 0 && len(n.Body) == 0 && n.Kind == aExpression {
+		return name
+	}
+	parts := []string{name}
+	for _, p := range n.Params {
+		parts = append(parts, sexpr(p))
+	}
+	for _, b := range n.Body {
+		parts = append(parts, sexpr(b))
+	}
+	return "(" + strings.Join(parts, " ") + ")"
+}
+
+func parseString(t *testing.T, source string) (Node, error) {
+	t.Helper()
+	tokens, err := Tokenize([]byte(source))
+	if err != nil {
+		t.Fatalf("Tokenize(%q): %v", source, err)
+	}
+	return Parse(tokens)
+}
+
+func TestParserPrecedence(t *testing.T) {
+	tests := []struct {
+		source string
+		want   string
+	}{
+		{"1 + 2 * 3", "(+ 1 (* 2 3))"},
+		{"1 * 2 + 3", "(+ (* 1 2) 3)"},
+		{"1 + 2 * 3 + 4", "(+ (+ 1 (* 2 3)) 4)"},
+		{"1 / 2 - 3 * 4", "(- (/ 1 2) (* 3 4))"},
+		{"a < b + 1", "(< a (+ b 1))"},
+		{"a + 1 >= b * 2", "(>= (+ a 1) (* b 2))"},
+		{"a != b == c", "(== (!= a b) c)"},
+		{"(1 + 2) * 3", "(* (paren (+ 1 2)) 3)"},
+		{"a * (b - c)", "(* a (paren (- b c)))"},
+		{"c = 4 + ((1 * a))", "(= c (+ 4 (paren (paren (* 1 a)))))"},
+		{"a = 1 + 2 * 3 + 4", "(= a (+ (+ 1 (* 2 3)) 4))"},
+		{"a = b < c", "(= a (< b c))"},
+		{"print(a + 1, b * 2)", "(print (+ a 1) (* b 2))"},
+		{"f() + 1", "(+ f 1)"},
+		{"a = true == nil", "(= a (== true nil))"},
+		{"a = \"x\"", "(= a x)"},
+		{"x = 1 +\n 2", "(= x (+ 1 2))"},
+		{"x = (1\n + 2)", "(= x (paren (+ 1 2)))"},
+		{"a || b && c", "(|| a (&& b c))"},
+		{"a && b || c && d", "(|| (&& a b) (&& c d))"},
+		{"a > 0 && b < 10", "(&& (> a 0) (< b 10))"},
+		{"a == 1 || b != 2", "(|| (== a 1) (!= b 2))"},
+		{"!a && !b", "(&& (! a) (! b))"},
+		{"!a == b", "(== (! a) b)"},
+		{"!!f(1)", "(! (! (f 1)))"},
+		{"!(a || b)", "(! (paren (|| a b)))"},
+		{"x = a &&\n b", "(= x (&& a b))"},
+		{"a % b * c", "(* (% a b) c)"},
+		{"a + b % c", "(+ a (% b c))"},
+		{"-a * b", "(* (- a) b)"},
+		{"3 * -a", "(* 3 (- a))"},
+		{"a - -b", "(- a (- b))"},
+		{"- -a + +b", "(+ (- (- a)) (+ b))"},
+		{"-f(1) % 2", "(% (- (f 1)) 2)"},
+		{"-(a + 1)", "(- (paren (+ a 1)))"},
+		{"!-a", "(! (- a))"},
+		{"a[0]", "([ a 0)"},
+		{"a[i + 1] * 2", "(* ([ a (+ i 1)) 2)"},
+		{"-a[0][1]", "(- ([ ([ a 0) 1))"},
+		{"f(1)[0]", "([ (f 1) 0)"},
+		{"[1, 2][0]", "([ ([ 1 2) 0)"},
+		{"[]", "([)"},
+		{"[1, [2, 3], \"a\"]", "([ 1 ([ 2 3) a)"},
+		{"x = [\n 1,\n 2,\n]", "(= x ([ 1 2))"},
+		{"m = {}", "(= m ({))"},
+		{"m = {\"a\": 1, 2: b + 1}", "(= m ({ a 1 2 (+ b 1)))"},
+		{"m = {\n \"a\": {\"b\": [1]},\n}", "(= m ({ a ({ b ([ 1))))"},
+		{"print({\"a\": 1}[\"a\"])", "(print ([ ({ a 1) a))"},
+		{"m[\"k\"] = {}", "(= ([ m k) ({))"},
+	}
+	for _, tt := range tests {
+		ast, err := parseString(t, tt.source)
+		if err != nil {
+			t.Errorf("parse(%q): unexpected error %v", tt.source, err)
+			continue
+		}
+		if got := sexpr(ast); got != tt.want {
+			t.Errorf("parse(%q) = %s, want %s", tt.source, got, tt.want)
+		}
+	}
+}
+
+func TestParserAssociativity(t *testing.T) {
+	tests := []struct {
+		source string
+		want   string
+	}{
+		{"a - b - c", "(- (- a b) c)"},
+		{"a / b / c", "(/ (/ a b) c)"},
+		{"a - b + c", "(+ (- a b) c)"},
+		{"a * b / c", "(/ (* a b) c)"},
+		{"a < b < c", "(< (< a b) c)"},
+		{"a = b = 1", "(= a (= b 1))"},
+		{"a += b -= 1", "(+= a (+ a (-= b (- b 1))))"},
+	}
+	for _, tt := range tests {
+		ast, err := parseString(t, tt.source)
+		if err != nil {
+			t.Errorf("parse(%q): unexpected error %v", tt.source, err)
+			continue
+		}
+		if got := sexpr(ast); got != tt.want {
+			t.Errorf("parse(%q) = %s, want %s", tt.source, got, tt.want)
+		}
+	}
+}
+
+func TestParserStatements(t *testing.T) {
+	tests := []struct {
+		source string
+		want   string
+	}{
+		{"a = 1\nprint(a)", "(= a 1); (print a)"},
+		{"a = 1; b = 2", "(= a 1); (= b 2)"},
+		{"a = 1\r\nb = 2\r\nprint(a, b)\r\n", "(= a 1); (= b 2); (print a b)"},
+		{"if (a) {\r\n b = 1\r\n}\r\nelse {\r\n}", "(if a {(= b 1)} {})"},
+		{"if (a < 0) {print(a)} else {print(b)}", "(if (< a 0) {(print a)} {(print b)})"},
+		{"if (a) {\n}\nelse {\n b = 1\n}", "(if a {} {(= b 1)})"},
+		{"if (a) {}\nb = 1", "(if a {}); (= b 1)"},
+		{"while (a < 0) { a = a + 1 }", "(while (< a 0) {(= a (+ a 1))})"},
+		{"for (b=0;b<3;b=b+1) { print(b) }", "(for (= b 0) ; (< b 3) ; (= b (+ b 1)) {(print b)})"},
+		{"for (;;) {}", "(for _ ; _ ; _ {})"},
+		{"for (b = 0; b < 3; b++) {}", "(for (= b 0) ; (< b 3) ; (++ b (+ b 1)) {})"},
+		{"for (b = 9; b; b -= 2) { c-- }", "(for (= b 9) ; b ; (-= b (- b 2)) {(-- c (- c 1))})"},
+		{"a *= 2; a /= 3; a %= 4", "(*= a (* a 2)); (/= a (/ a 3)); (%= a (% a 4))"},
+		{"a = -1", "(= a (- 1))"},
+		{"a[i] = 1", "(= ([ a i) 1)"},
+		{"a[0][1] += 2", "(+= ([ ([ a 0) 1) 2)"},
+		{"a[i]++", "(++ ([ a i) 1)"},
+		{"a\n[1]", "a; ([ 1)"},
+		{"{ a }", "{a}"},
+		{"if (a) { m = {} }", "(if a {(= m ({))})"},
+		{"return_value = {\"a\": 1}\n{ b = 2 }", "(= return_value ({ a 1)); {(= b 2)}"},
+		{"{ a = 1\n { b = 2 } }", "{(= a 1) {(= b 2)}}"},
+		{"int f(int a, int b) { return a + b; }", "(f a b {(return (+ a b))})"},
+		{"void g() {\n return\n}\ng()", "(g {(return)}); g"},
+		{"print()\nprint(a, \"b\", 1)", "print; (print a b 1)"},
+		{"len = len(\"ab\")", "(= len (len ab))"},
+		{"iffy = 1\nformat = iffy\nprint(format)", "(= iffy 1); (= format iffy); (print format)"},
+		{"int h(int n) { if (n) { return h(n - 1) } }", "(h n {(if n {(return (h (- n 1)))})})"},
+		{"while (a) { break; continue }", "(while a {(break) (continue)})"},
+		{"outer: for (;;) { while (a) { continue outer } }", "(for:outer _ ; _ ; _ {(while a {(continue:outer)})})"},
+		{"a:\nwhile (a) { if (b) { break a } else { break } }", "(while:a a {(if b {(break:a)} {(break)})})"},
+		{"while (a) { int f() { while (b) { break } } }", "(while a {(f {(while b {(break)})})})"},
+		{"break_count = 1\ncontinued = 2", "(= break_count 1); (= continued 2)"},
+		{"if (a) {} else if (b) {}", "(if a {} (if b {}))"},
+		{"if (a) {\n} else if (b) {\n} else if (c) {\n} else {\n d = 1\n}", "(if a {} (if b {} (if c {} {(= d 1)})))"},
+		{"if (a) {}", "(if a {})"},
+		{"switch (a) {}", "(switch a)"},
+		{"switch (a + 1) {\ncase 1, b:\n c = 1\n d = 2\ncase {\"k\": 1}: {}\ndefault:\n}", "(switch (+ a 1) (case 1 b (= c 1) (= d 2)) (case ({ k 1) {}) (default))"},
+		{"switch (a) { default: b = 1; case 2: b = 2 }", "(switch a (default (= b 1)) (case 2 (= b 2)))"},
+		{"while (a) { switch (b) { case 1: continue } }", "(while a {(switch b (case 1 (continue)))})"},
+		{"switch (x) { case 1, 2: print(\"a\") default: print(\"b\") }", "(switch x (case 1 2 (print a)) (default (print b)))"},
+		{"switch (a) { case 1: b = 1; c = 2 case 2: if (b) { break } }", "(switch a (case 1 (= b 1) (= c 2)) (case 2 (if b {(break)})))"},
+		{"x: while (a) { switch (b) { case 1: break x } }", "(while:x a {(switch b (case 1 (break:x)))})"},
+	}
+	for _, tt := range tests {
+		ast, err := parseString(t, tt.source)
+		if err != nil {
+			t.Errorf("parse(%q): unexpected error %v", tt.source, err)
+			continue
+		}
+		if got := sexpr(ast); got != tt.want {
+			t.Errorf("parse(%q) = %s, want %s", tt.source, got, tt.want)
+		}
+	}
+}
+
+func TestParserErrors(t *testing.T) {
+	tests := []string{
+		"1 +",
+		"1 = 2",
+		"a + b = 2",
+		"(1 + 2",
+		"a = 1 2",
+		"print(a b)",
+		"if a {}",
+		"while (a) print(a)",
+		"for (a = 0; a < 1) {}",
+		"{ a = 1",
+		")",
+		"return 1",
+		"if (a) { return }",
+		"int f(int a, int a) {}",
+		"int f(a) {}",
+		"int f(int a) { return a b }",
+		"a = 99999999999999999999",
+		"true = 1",
+		"print = 1",
+		"a = print",
+		"int len(int a) { return a }",
+		"1 += 2",
+		"f() = 1",
+		"f() += 1",
+		"-a = 1",
+		"1++",
+		"print(a++)",
+		"a = b++",
+		"for (a = 0; a++; ) {}",
+		"a = 1 -",
+		"a[",
+		"a[]",
+		"a[1",
+		"[1, 2",
+		"[1 2]",
+		"[,]",
+		"m = {\"a\" 1}",
+		"m = {\"a\": 1 \"b\": 2}",
+		"m = {\"a\":}",
+		"m = {\"a\": 1",
+		"{\"a\": 1}",
+		"break",
+		"if (a) { continue }",
+		"while (a) { int f() { break } }",
+		"while (a) { break 1 }",
+		"while (a) { break b }",
+		"a: while (b) {}\nwhile (c) { continue a }",
+		"a: print(1)",
+		"a:",
+		"break = 1",
+		"if (a) {} else b = 1",
+		"if (a) {} else if {}",
+		"switch a {}",
+		"switch (a) { b = 1 }",
+		"switch (a) { case: }",
+		"switch (a) { case 1 }",
+		"switch (a) { case 1 2: }",
+		"switch (a) { default 1: }",
+		"switch (a) { case 1: continue }",
+		"switch (a) { case 1: break b }",
+		"switch (a) { case 1: int f() { break } }",
+		"switch (a) { case 1: { b = 1 case 2: } }",
+		"a = 1 case 2",
+		"switch (a) { default: default: }",
+		"switch (a) { case 1:",
+		"case 1: b = 2",
+		"default = 1",
+	}
+	for _, source := range tests {
+		if ast, err := parseString(t, source); err == nil {
+			t.Errorf("parse(%q) = %s, want an error", source, sexpr(ast))
+		}
+	}
+}
+
+func TestParserRecovery(t *testing.T) {
+	tests := []struct {
+		source string
+		want   string
+		errors int
+	}{
+		{"a = 1 +\nb = 2\nc = )\nprint(b)", "(print b)", 2},
+		{"a = (1 +\n\nb = 2", "", 1},
+		{"a = 1; b = ; c = 3", "(= a 1); (= c 3)", 1},
+		{"if (a) { b = * 2\n c = 3 }\nd = 4", "(if a {(= c 3)}); (= d 4)", 1},
+		{"} a = 1\nb = 2", "(= a 1); (= b 2)", 1},
+		{"int f(int a) {\n return a +\n}\nx = f(1)\ny = ", "(f a {}); (= x (f 1))", 2},
+		{"while (a) {\n b = 1\n", "", 1},
+		{"a = 1 2 3 if (a) { b = 1 }", "(if a {(= b 1)})", 1},
+		{"return 1\na = 1", "(= a 1)", 1},
+		{"if (a > 0 {\n print(a)\n}\nb = 1", "(= b 1)", 1},
+		{"int f(int a {\n return a\n}\nb = 1", "(= b 1)", 1},
+		{"while (x) print(1) }\nb = 1", "(= b 1)", 1},
+		{"} }\na = 1", "(= a 1)", 2},
+		{"while (a) {\n b = (\n}\nbreak\na = 1", "(while a {}); (= a 1)", 2},
+		{"switch (a) {\ncase 1 2:\n b = 1\ncase 3:\n c = 1 +\n}\nd = 1", "(switch a (case 3)); (= d 1)", 2},
+		{"switch (a) {\n b = 1\ncase 1:\n}", "(switch a (case 1))", 1},
+	}
+	for _, tt := range tests {
+		ast, err := parseString(t, tt.source)
+		var list ErrorList
+		if !errors.As(err, &list) {
+			t.Errorf("parse(%q) gave %v, want an ErrorList", tt.source, err)
+			continue
+		}
+		if len(list) != tt.errors {
+			t.Errorf("parse(%q) gave %d errors, want %d:\n%v", tt.source, len(list), tt.errors, err)
+		}
+		if got := sexpr(ast); got != tt.want {
+			t.Errorf("parse(%q) = %s, want %s", tt.source, got, tt.want)
+		}
+	}
+}
+
+// TestParserLexerErrors checks that the errors of the tokenizer and of the
+// parser come together in the order of the source
+func TestParserLexerErrors(t *testing.T) {
+	tests := []struct {
+		source string
+		want   string
+	}{
+		{"a = (1 +\nc = 3 $", "trying to assign to a non-id target at line 2, col 3\ninvalid character '$' at line 2, col 7"},
+		{"a = 1 $ 2 # 3", "invalid character '$' at line 1, col 7\nunexpected token `2`, should be the end of the statement at line 1, col 9\ninvalid character '#' at line 1, col 11"},
+		{"x = \"abc\nprint(x", "unterminated string at line 1, col 5\nunexpected end of tokens, should be `,` or `)` at line 2, col 8"},
+		{"a = 1 # 2", "invalid character '#' at line 1, col 7\nunexpected token `2`, should be the end of the statement at line 1, col 9"},
+		{"a = \"b\\q\"", "invalid escape sequence \\q at line 1, col 7"},
+	}
+	for _, tt := range tests {
+		tokens, lexErr := Tokenize([]byte(tt.source))
+		_, err := Parse(tokens)
+		err = JoinErrors(lexErr, err)
+		if err == nil || err.Error() != tt.want {
+			t.Errorf("parse(%q) gave errors\n%v\nwant\n%s", tt.source, err, tt.want)
+		}
+	}
+	if err := JoinErrors(nil, nil); err != nil {
+		t.Errorf("JoinErrors(nil, nil) = %v, want nil", err)
+	}
+}
+
+func TestParserSyntaxError(t *testing.T) {
+	tests := []struct {
+		source   string
+		line     int
+		col      int
+		found    int
+		expected string
+		message  string
+	}{
+		{"a = 1 +", 1, 8, 0, "an expression", ""},
+		{"print(a b)", 1, 9, tIdentifier, "`,` `)`", ""},
+		{"a = 1\nif a {}", 2, 4, tIdentifier, "`(`", ""},
+		{"1 = 2", 1, 3, tEqual, "", "trying to assign to a non-id target"},
+		{"int f(int a, int a) {}", 1, 18, tIdentifier, "", "duplicate param a"},
+		{"  return", 1, 3, tReturn, "", "return outside of a function"},
+		{"for (;;) {}\ncontinue", 2, 1, tContinue, "", "continue outside of a loop"},
+		{"x: while (a) { break y }", 1, 22, tIdentifier, "", "no loop labeled y around this break"},
+		{"switch (a) {\ncase 1 2:\n}", 2, 8, tInteger, "`:` `,`", ""},
+		{"switch (a) {\ndefault:\n  default:\n}", 3, 3, tDefault, "", "more than one default in a switch"},
+	}
+	for _, tt := range tests {
+		_, err := parseString(t, tt.source)
+		var syntaxErr *SyntaxError
+		if !errors.As(err, &syntaxErr) {
+			t.Errorf("parse(%q) gave %v, want a SyntaxError", tt.source, err)
+			continue
+		}
+		got := *syntaxErr
+		if got.Line != tt.line || got.Col != tt.col || got.Found.kind != tt.found ||
+			strings.Join(got.Expected, " ") != tt.expected || got.Message != tt.message {
+			t.Errorf("parse(%q) gave %+v", tt.source, got)
+		}
+	}
+}
+
+// TestParserConcurrent parses valid and broken sources from many goroutines
+// at once, every parse has to give what it gives on its own. Run it with
+// -race.
+func TestParserConcurrent(t *testing.T) {
+	sources := []string{
+		"a = 1 + 2 * 3\nprint(a)",
+		"int f(int n) {\n\twhile (n) { n--; if (n) { return n } }\n}",
+		"outer: for (i = 0; i < 3; i++) { break outer }",
+		"int f(int a) {\n return a +",
+		"while (a) {\n break",
+		"return 1",
+		"break",
+		"a = (1 +\n\nb = 2",
+	}
+	type result struct {
+		ast string
+		err string
+	}
+	parse := func(source string) result {
+		tokens, err := Tokenize([]byte(source))
+		if err != nil {
+			return result{err: err.Error()}
+		}
+		ast, err := Parse(tokens)
+		if err != nil {
+			return result{sexpr(ast), err.Error()}
+		}
+		return result{sexpr(ast), ""}
+	}
+	want := make([]result, len(sources))
+	for i, source := range sources {
+		want[i] = parse(source)
+	}
+
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for n := 0; n < 50; n++ {
+				i := (g + n) % len(sources)
+				if got := parse(sources[i]); got != want[i] {
+					t.Errorf("parse(%q) = %+v, want %+v", sources[i], got, want[i])
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+}
+
+// TestParserCleanState checks that a parse that failed inside a function or
+// a loop leaves nothing behind for the next one
+func TestParserCleanState(t *testing.T) {
+	for _, broken := range []string{"int f(int a) {\n return a +", "while (a) {\n b = (", "x: for (;;) {"} {
+		if _, err := parseString(t, broken); err == nil {
+			t.Fatalf("parse(%q) did not fail", broken)
+		}
+		for _, source := range []string{"return 1", "break", "continue x"} {
+			if ast, err := parseString(t, source); err == nil {
+				t.Errorf("parse(%q) after parse(%q) = %s, want an error", source, broken, sexpr(ast))
+			}
+		}
+		if ast, err := parseString(t, "a = 1"); err != nil || sexpr(ast) != "(= a 1)" {
+			t.Errorf("parse(\"a = 1\") after parse(%q) = %s, %v", broken, sexpr(ast), err)
+		}
+	}
+}
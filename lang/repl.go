@@ -0,0 +1,173 @@
+/**
+ * ============================================================================
+ *                                   ( ◕‿◕)つ
+ *                                  THE REPL
+ * ============================================================================
+ */
+
+package lang
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+const replHelp = `type a statement or an expression to run it, expressions print their value.
+an unclosed ( or { keeps reading on the next line.
+
+meta-commands:
+  :tokens  print the tokens of the last input
+  :ast     print the ast of the last input
+  :vars    print the variables
+  :reset   forget all the variables
+  :help    print this help
+  :quit    leave the repl
+`
+
+// REPL reads the input one line or block at a time and runs it on the same
+// Interpreter, so the variables stay alive between the inputs
+func REPL(in io.Reader, out io.Writer) int {
+	scanner := bufio.NewScanner(in)
+	var source strings.Builder
+	var lastTokens []Token
+	var lastAst Node
+	interp := NewInterpreter(out)
+
+	fmt.Fprint(out, ">>> ")
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		if source.Len() == 0 && strings.HasPrefix(strings.TrimSpace(line), ":") {
+			switch strings.TrimSpace(line) {
+			case ":tokens":
+				for _, t := range lastTokens {
+					fmt.Fprintln(out, t)
+				}
+			case ":ast":
+				j, _ := json.MarshalIndent(lastAst, "", "  ")
+				fmt.Fprintf(out, "%s\n", j)
+			case ":vars":
+				names := make([]string, 0, len(interp.variables))
+				for name := range interp.variables {
+					names = append(names, name)
+				}
+				sort.Strings(names)
+				for _, name := range names {
+					fmt.Fprintf(out, "%s = %s\n", name, interp.variables[name])
+				}
+			case ":reset":
+				interp.Reset()
+			case ":help":
+				fmt.Fprint(out, replHelp)
+			case ":quit", ":q":
+				return 0
+			default:
+				fmt.Fprintf(out, "unknown meta-command %s, try :help\n", strings.TrimSpace(line))
+			}
+			fmt.Fprint(out, ">>> ")
+			continue
+		}
+
+		source.WriteString(line)
+		source.WriteByte('\n')
+
+		// 词法分析，its errors are reported with the ones of the parser
+		var tokens []Token
+		var lexErr error
+		err := Protect("lexer", func() error {
+			tokens, lexErr = Tokenize([]byte(source.String()))
+			return nil
+		})
+		if err != nil {
+			fmt.Fprintln(out, err)
+			source.Reset()
+			fmt.Fprint(out, ">>> ")
+			continue
+		}
+		if lexErr == nil && unclosed(tokens) {
+			fmt.Fprint(out, "... ")
+			continue
+		}
+		source.Reset()
+		lastTokens = tokens
+
+		// 语法分析
+		var ast Node
+		err = Protect("parser", func() (err error) {
+			ast, err = Parse(tokens)
+			return JoinErrors(lexErr, err)
+		})
+		if err != nil {
+			fmt.Fprintln(out, err)
+			fmt.Fprint(out, ">>> ")
+			continue
+		}
+		lastAst = ast
+
+		// 执行，表达式打印结果
+		err = Protect("runtime", func() error {
+			for i := range ast.Body {
+				result, err := interp.run(&ast.Body[i])
+				if err != nil {
+					return err
+				}
+				if isBareExpression(&ast.Body[i]) {
+					fmt.Fprintln(out, result)
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			fmt.Fprintln(out, err)
+		}
+		fmt.Fprint(out, ">>> ")
+	}
+	fmt.Fprintln(out)
+	return 0
+}
+
+// unclosed tells whether there are more `(`, `[` or `{` than the closing ones
+func unclosed(tokens []Token) bool {
+	parens, braces := 0, 0
+	for _, t := range tokens {
+		switch t.kind {
+		case tLParen, tLBracket:
+			parens++
+		case tRParen, tRBracket:
+			parens--
+		case tLBrace:
+			braces++
+		case tRBrace:
+			braces--
+		}
+	}
+	return parens > 0 || braces > 0
+}
+
+// isBareExpression tells whether the value of a statement should be printed
+func isBareExpression(n *Node) bool {
+	switch n.Kind {
+	case aNumberLiteral, aFloatLiteral, aStringLiteral, aBoolLiteral, aNilLiteral, aListLiteral, aIndexExpression,
+		aMapLiteral:
+		return true
+	case aExpression:
+		return n.Name != "print"
+	}
+	return false
+}
+
+// Protect turns a panic inside a stage into an error, so a bug of the
+// language does not take down the repl, or the other files of a command
+func Protect(stage string, f func() error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = errors.New(fmt.Sprint(stage, " error: ", r))
+		}
+	}()
+	return f()
+}
@@ -0,0 +1,51 @@
+package lang
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// repl feeds the lines to the REPL and gives back what it wrote, without the
+// prompts
+func repl(t *testing.T, lines ...string) string {
+	t.Helper()
+	var out bytes.Buffer
+	if code := REPL(strings.NewReader(strings.Join(lines, "\n")+"\n"), &out); code != 0 {
+		t.Fatalf("REPL(%q) gave back %d", lines, code)
+	}
+	return strings.NewReplacer(">>> ", "", "... ", "").Replace(out.String())
+}
+
+func TestREPL(t *testing.T) {
+	tests := []struct {
+		lines []string
+		want  string
+	}{
+		{[]string{"xs = [1, [2, 3]]", "xs", "xs[1]", "xs[1][0]", "[4]"}, "[1, [2, 3]]\n[2, 3]\n2\n[4]\n\n"},
+		{[]string{"m = {\"k\": {\"a\": 1}}", "m[\"k\"]", "m", "({})"}, "{\"a\": 1}\n{\"k\": {\"a\": 1}}\n{}\n\n"},
+		// the variables and the functions stay from one input to the next
+		{[]string{"a = 1", "int f(int n) { return n + a }", "a = 2", "f(1)"}, "3\n\n"},
+		// an unclosed `(`, `[` or `{` keeps reading
+		{[]string{"if (a) {", "print(1)", "} else {", "print(2)", "}"}, "runtime error: undefined variable a at line 1, col 5\n\n"},
+		{[]string{"xs = [1,", "2]", "print(xs,", "len(xs))"}, "[1, 2] 2\n\n"},
+		// an error does not end the repl, nor lose the variables
+		{[]string{"a = 1", "print(a / 0)", "b = )", "print(a)"}, "runtime error: division by zero at line 1, col 9\nunexpected token `)`, should be an expression at line 1, col 5\n1\n\n"},
+		{[]string{"a = \"x", "1 + 1"}, "unterminated string at line 1, col 5\n2\n\n"},
+		// a bad character does not wait for more lines, the errors of the
+		// parser come with it
+		{[]string{"xs = (1 $ 2", "1 + 1"}, "invalid character '$' at line 1, col 9\nunexpected token `2`, should be `)` at line 1, col 11\n2\n\n"},
+		// meta-commands
+		{[]string{"b = 2", "a = [1]", ":vars"}, "a = [1]\nb = 2\n\n"},
+		{[]string{"a = 1", ":reset", ":vars", "a"}, "runtime error: undefined variable a at line 1, col 1\n\n"},
+		{[]string{"c = 2", ":tokens"}, "1:1\tIdentifier\tc\n1:3\tAssign\t=\n1:5\tInteger\t2\n1:6\tNewLine\t\\n\n\n"},
+		{[]string{":nope"}, "unknown meta-command :nope, try :help\n\n"},
+		{[]string{":help"}, replHelp + "\n"},
+		{[]string{"1", ":quit", "2"}, "1\n"},
+	}
+	for _, tt := range tests {
+		if got := repl(t, tt.lines...); got != tt.want {
+			t.Errorf("REPL(%q) wrote %q, want %q", tt.lines, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,603 @@
+package lang
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// maxFrames stops a runaway recursion before it blows up the Go stack
+const maxFrames = 10000
+
+// maxTrace is how many calls of the trace a RuntimeError prints
+const maxTrace = 10
+
+// RuntimeError stops a running program. token is where it happened and the
+// trace is the stack of the calls that led there, the innermost first.
+type RuntimeError struct {
+	token Token
+	// code tells the kind of the error, like runtime/division-by-zero
+	code    string
+	message string
+	trace   []traceEntry
+	// err is what caused it, like a *TypeError
+	err error
+}
+
+// traceEntry is one call in the trace of a RuntimeError
+type traceEntry struct {
+	function string
+	// call is where the function was called
+	call Token
+}
+
+func (e *RuntimeError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "runtime error: %s at line %d, col %d", e.message, e.token.line, e.token.col)
+	for i, entry := range e.trace {
+		if i == maxTrace {
+			fmt.Fprintf(&b, "\n\t... %d more calls", len(e.trace)-maxTrace)
+			break
+		}
+		fmt.Fprintf(&b, "\n\tin %s called at line %d, col %d", entry.function, entry.call.line, entry.call.col)
+	}
+	return b.String()
+}
+
+func (e *RuntimeError) Unwrap() error {
+	return e.err
+}
+
+// runtimeError builds a RuntimeError without a trace, the backend that
+// gives it back fills the trace in
+func runtimeError(t Token, code string, format string, args ...interface{}) *RuntimeError {
+	return &RuntimeError{token: t, code: code, message: fmt.Sprintf(format, args...)}
+}
+
+// Interpreter runs an ast. It owns the variables of the program, so every
+// instance is isolated from the others and can run in its own goroutine.
+type Interpreter struct {
+	// variables map[identifier]value, the globals
+	variables map[string]Value
+	// functions map[name]declaration
+	functions map[string]*Node
+	// host map[name]function registered by the Go program
+	host map[string]HostFunc
+	// frames of the functions being called, the last one is running
+	frames []*frame
+	// jump is the `break` or `continue` on its way out to its loop, the
+	// statements in between stop running
+	jump *Node
+	// out is where print writes to
+	out io.Writer
+}
+
+// frame is one call of a function. The params and the variables assigned
+// inside the function live in the frame, the globals can be read but not
+// assigned from inside a function.
+type frame struct {
+	function *Node
+	// call is where the function was called
+	call Token
+	// variables map[identifier]value
+	variables map[string]Value
+	// returned is set by `return`, the statements of the function stop
+	// running until the call gives back returnValue
+	returned    bool
+	returnValue Value
+}
+
+// NewInterpreter creates an Interpreter printing to out
+func NewInterpreter(out io.Writer) *Interpreter {
+	return &Interpreter{
+		variables: make(map[string]Value),
+		functions: make(map[string]*Node),
+		host:      make(map[string]HostFunc),
+		out:       out,
+	}
+}
+
+// Reset forgets all the variables and functions, the globals set by the host
+// included, so the next run starts from scratch. The host functions stay.
+func (in *Interpreter) Reset() {
+	in.variables = make(map[string]Value)
+	in.functions = make(map[string]*Node)
+	in.frames = nil
+	in.jump = nil
+}
+
+// fail fills the trace of the calls into err
+func (in *Interpreter) fail(err *RuntimeError) error {
+	if err.trace == nil {
+		for i := len(in.frames) - 1; i >= 0; i-- {
+			err.trace = append(err.trace, traceEntry{in.frames[i].function.Name, in.frames[i].call})
+		}
+	}
+	return err
+}
+
+// malformed is the error of a node the parser would never make
+func (in *Interpreter) malformed(n *Node) error {
+	return in.fail(runtimeError(n.token, codeInternal, "malformed %s node", n.Name))
+}
+
+// lookup reads a variable, the locals of the running function first
+func (in *Interpreter) lookup(n *Node) (Value, error) {
+	if len(in.frames) > 0 {
+		if value, ok := in.frames[len(in.frames)-1].variables[n.Name]; ok {
+			return value, nil
+		}
+	}
+	value, ok := in.variables[n.Name]
+	if !ok {
+		return Value{}, in.fail(runtimeError(n.token, codeUndefinedVariable, "undefined variable %s", n.Name))
+	}
+	return value, nil
+}
+
+// assign a variable, inside a function it is always a local one
+func (in *Interpreter) assign(name string, value Value) {
+	if len(in.frames) > 0 {
+		in.frames[len(in.frames)-1].variables[name] = value
+		return
+	}
+	in.variables[name] = value
+}
+
+// returning tells whether a `return` is on its way out of the running function
+func (in *Interpreter) returning() bool {
+	return len(in.frames) > 0 && in.frames[len(in.frames)-1].returned
+}
+
+// jumping tells whether a `break` or `continue` is on its way out to its loop
+func (in *Interpreter) jumping() bool {
+	return in.jump != nil
+}
+
+// stopLoop takes the `break` or `continue` meant for the loop n and tells
+// whether n has to stop. A jump to a loop around n stops n as well, and goes
+// on out to its own loop.
+func (in *Interpreter) stopLoop(n *Node) bool {
+	if in.returning() {
+		return true
+	}
+	if in.jump == nil {
+		return false
+	}
+	if in.jump.Value != "" && in.jump.Value != n.Value {
+		return true
+	}
+	stop := in.jump.Kind == aStatementBreak
+	in.jump = nil
+	return stop
+}
+
+// runParams works out all the params of a call, from left to right
+func (in *Interpreter) runParams(n *Node) ([]Value, error) {
+	params := make([]Value, len(n.Params))
+	for i := range n.Params {
+		v, err := in.run(&n.Params[i])
+		if err != nil {
+			return nil, err
+		}
+		params[i] = v
+	}
+	return params, nil
+}
+
+// runCall calls the builtin, the function or the host function named by n,
+// the params are worked out before the function is looked up
+func (in *Interpreter) runCall(n *Node) (Value, error) {
+	params, err := in.runParams(n)
+	if err != nil {
+		return Value{}, err
+	}
+	if b := lookupBuiltin(n.Name); b != nil {
+		if err := b.checkParams(n.token, len(params)); err != nil {
+			return Value{}, in.fail(err)
+		}
+		v, err := b.call(in.out, n.token, params)
+		if err != nil {
+			return Value{}, in.fail(err)
+		}
+		return v, nil
+	}
+	function, ok := in.functions[n.Name]
+	if !ok {
+		if f, ok := in.host[n.Name]; ok {
+			v, err := callHost(f, n.token, params)
+			if err != nil {
+				return Value{}, in.fail(err)
+			}
+			return v, nil
+		}
+		return Value{}, in.fail(runtimeError(n.token, codeUndefinedFunction, "undefined function %s", n.Name))
+	}
+	if len(params) != len(function.Params) {
+		return Value{}, in.fail(runtimeError(n.token, codeWrongArity, "%s wants %d params but got %d", function.Name, len(function.Params), len(params)))
+	}
+	if len(in.frames) >= maxFrames {
+		return Value{}, in.fail(runtimeError(n.token, codeStackOverflow, "stack overflow calling %s", function.Name))
+	}
+	if len(function.Body) == 0 {
+		return Value{}, in.malformed(function)
+	}
+	f := &frame{function: function, call: n.token, variables: make(map[string]Value, len(params))}
+	for i := range function.Params {
+		f.variables[function.Params[i].Name] = params[i]
+	}
+	in.frames = append(in.frames, f)
+	defer func() {
+		in.frames = in.frames[:len(in.frames)-1]
+	}()
+	if _, err := in.run(&function.Body[0]); err != nil {
+		return Value{}, err
+	}
+	return f.returnValue, nil
+}
+
+func (in *Interpreter) runExpression(n *Node) (expressionResult Value, err error) {
+	switch n.Name {
+	case "(":
+		if len(n.Params) != 1 {
+			return Value{}, in.malformed(n)
+		}
+		return in.run(&n.Params[0])
+	}
+	// `-` is a prefix operator with one param and an infix one with two
+	if prefixOperators[n.token.kind] && len(n.Params) == 1 {
+		v, err := in.run(&n.Params[0])
+		if err != nil {
+			return Value{}, err
+		}
+		v, opErr := unaryOperation(n.token, v)
+		if opErr != nil {
+			return Value{}, in.fail(opErr)
+		}
+		return v, nil
+	}
+	if _, ok := infixOperators[n.token.kind]; ok {
+		if len(n.Params) != 2 {
+			return Value{}, in.malformed(n)
+		}
+		left, err := in.run(&n.Params[0])
+		if err != nil {
+			return Value{}, err
+		}
+		// && and || stop as soon as the left side decides
+		switch {
+		case n.token.kind == tAnd && !left.truthy():
+			return BoolValue(false), nil
+		case n.token.kind == tOr && left.truthy():
+			return BoolValue(true), nil
+		}
+		right, err := in.run(&n.Params[1])
+		if err != nil {
+			return Value{}, err
+		}
+		v, opErr := binaryOperation(n.token, left, right)
+		if opErr != nil {
+			return Value{}, in.fail(opErr)
+		}
+		return v, nil
+	}
+	// the parser gives calls non-nil params, even without arguments
+	if n.Params != nil {
+		return in.runCall(n)
+	}
+	return in.lookup(n)
+}
+
+func (in *Interpreter) runStatement(n *Node) (expressionResult Value, err error) {
+	l := len(n.Body)
+	for i := 0; i < l && !in.returning() && !in.jumping(); i++ {
+		if _, err = in.run(&n.Body[i]); err != nil {
+			return Value{}, err
+		}
+	}
+	return Value{}, nil
+}
+
+// runAssignmentStatement gives back the assigned value, so `a = b = 1` works
+func (in *Interpreter) runAssignmentStatement(n *Node) (expressionResult Value, err error) {
+	if len(n.Params) != 2 {
+		return Value{}, in.malformed(n)
+	}
+	target := &n.Params[0]
+	if target.Kind == aIndexExpression {
+		return in.runIndexAssignment(target, &n.Params[1])
+	}
+	if expressionResult, err = in.run(&n.Params[1]); err != nil {
+		return Value{}, err
+	}
+	in.assign(target.Name, expressionResult)
+	return expressionResult, nil
+}
+
+// runIndexAssignment runs `a[i] = value`, the list and the index first
+func (in *Interpreter) runIndexAssignment(target *Node, value *Node) (Value, error) {
+	if len(target.Params) != 2 {
+		return Value{}, in.malformed(target)
+	}
+	l, err := in.run(&target.Params[0])
+	if err != nil {
+		return Value{}, err
+	}
+	index, err := in.run(&target.Params[1])
+	if err != nil {
+		return Value{}, err
+	}
+	v, err := in.run(value)
+	if err != nil {
+		return Value{}, err
+	}
+	if err := setIndex(target.token, l, index, v); err != nil {
+		return Value{}, in.fail(err)
+	}
+	return v, nil
+}
+
+// runCompoundIndexAssignment runs `a[i] += value`, the list and the index
+// are worked out once
+func (in *Interpreter) runCompoundIndexAssignment(n *Node) (Value, error) {
+	if len(n.Params) != 2 || len(n.Params[0].Params) != 2 {
+		return Value{}, in.malformed(n)
+	}
+	target := &n.Params[0]
+	l, err := in.run(&target.Params[0])
+	if err != nil {
+		return Value{}, err
+	}
+	index, err := in.run(&target.Params[1])
+	if err != nil {
+		return Value{}, err
+	}
+	old, opErr := indexValue(target.token, l, index)
+	if opErr != nil {
+		return Value{}, in.fail(opErr)
+	}
+	right, err := in.run(&n.Params[1])
+	if err != nil {
+		return Value{}, err
+	}
+	v, opErr := binaryOperation(n.token, old, right)
+	if opErr != nil {
+		return Value{}, in.fail(opErr)
+	}
+	if err := setIndex(target.token, l, index, v); err != nil {
+		return Value{}, in.fail(err)
+	}
+	return v, nil
+}
+
+func (in *Interpreter) runListLiteral(n *Node) (Value, error) {
+	items := make([]Value, len(n.Params))
+	for i := range n.Params {
+		v, err := in.run(&n.Params[i])
+		if err != nil {
+			return Value{}, err
+		}
+		items[i] = v
+	}
+	return ListValue(items...), nil
+}
+
+func (in *Interpreter) runMapLiteral(n *Node) (Value, error) {
+	entries := make([]Value, len(n.Params))
+	for i := range n.Params {
+		v, err := in.run(&n.Params[i])
+		if err != nil {
+			return Value{}, err
+		}
+		entries[i] = v
+	}
+	v, err := mapLiteral(n.token, entries)
+	if err != nil {
+		return Value{}, in.fail(err)
+	}
+	return v, nil
+}
+
+func (in *Interpreter) runIndexExpression(n *Node) (Value, error) {
+	if len(n.Params) != 2 {
+		return Value{}, in.malformed(n)
+	}
+	l, err := in.run(&n.Params[0])
+	if err != nil {
+		return Value{}, err
+	}
+	index, err := in.run(&n.Params[1])
+	if err != nil {
+		return Value{}, err
+	}
+	v, opErr := indexValue(n.token, l, index)
+	if opErr != nil {
+		return Value{}, in.fail(opErr)
+	}
+	return v, nil
+}
+
+func (in *Interpreter) runStatementIf(n *Node) (expressionResult Value, err error) {
+	if len(n.Params) != 1 || len(n.Body) == 0 {
+		return Value{}, in.malformed(n)
+	}
+	condition, err := in.run(&n.Params[0])
+	if err != nil {
+		return Value{}, err
+	}
+	if condition.truthy() {
+		return in.run(&n.Body[0])
+	} else if len(n.Body) > 1 {
+		return in.run(&n.Body[1])
+	}
+	return Value{}, nil
+}
+
+func (in *Interpreter) runStatementWhile(n *Node) (expressionResult Value, err error) {
+	if len(n.Params) != 1 {
+		return Value{}, in.malformed(n)
+	}
+	for {
+		condition, err := in.run(&n.Params[0])
+		if err != nil {
+			return Value{}, err
+		}
+		if !condition.truthy() {
+			break
+		}
+		if _, err = in.runStatement(n); err != nil {
+			return Value{}, err
+		}
+		if in.stopLoop(n) {
+			break
+		}
+	}
+	return Value{}, nil
+}
+
+func (in *Interpreter) runStatementFor(n *Node) (expressionResult Value, err error) {
+	if len(n.Params) != 5 {
+		return Value{}, in.malformed(n)
+	}
+	if _, err = in.run(&n.Params[0]); err != nil {
+		return Value{}, err
+	}
+	for {
+		// a left out condition is always true
+		if n.Params[2].Kind != aBlank {
+			condition, err := in.run(&n.Params[2])
+			if err != nil {
+				return Value{}, err
+			}
+			if !condition.truthy() {
+				break
+			}
+		}
+		if _, err = in.runStatement(n); err != nil {
+			return Value{}, err
+		}
+		// `continue` still runs the step
+		if in.stopLoop(n) {
+			break
+		}
+		if _, err = in.run(&n.Params[4]); err != nil {
+			return Value{}, err
+		}
+	}
+	return Value{}, nil
+}
+
+func (in *Interpreter) runStatementReturn(n *Node) (expressionResult Value, err error) {
+	if len(in.frames) == 0 {
+		return Value{}, in.fail(runtimeError(n.token, codeReturnOutsideFunction, "return outside of a function"))
+	}
+	f := in.frames[len(in.frames)-1]
+	if len(n.Params) > 0 {
+		if f.returnValue, err = in.run(&n.Params[0]); err != nil {
+			return Value{}, err
+		}
+	}
+	f.returned = true
+	return f.returnValue, nil
+}
+
+// runStatementSwitch runs the first case with a value equal to the subject,
+// or the default if none is
+func (in *Interpreter) runStatementSwitch(n *Node) (expressionResult Value, err error) {
+	if len(n.Params) != 1 {
+		return Value{}, in.malformed(n)
+	}
+	subject, err := in.run(&n.Params[0])
+	if err != nil {
+		return Value{}, err
+	}
+	var chosen, defaultCase *Node
+	for i := 0; i < len(n.Body) && chosen == nil; i++ {
+		c := &n.Body[i]
+		if c.token.kind == tDefault {
+			defaultCase = c
+		}
+		for j := range c.Params {
+			v, err := in.run(&c.Params[j])
+			if err != nil {
+				return Value{}, err
+			}
+			equal, opErr := binaryOperation(equalsToken(c.Params[j].token), subject, v)
+			if opErr != nil {
+				return Value{}, in.fail(opErr)
+			}
+			if equal.truthy() {
+				chosen = c
+				break
+			}
+		}
+	}
+	if chosen == nil {
+		chosen = defaultCase
+	}
+	if chosen == nil {
+		return Value{}, nil
+	}
+	expressionResult, err = in.runStatement(chosen)
+	// a `break` without a label is for the switch
+	if in.jump != nil && in.jump.Kind == aStatementBreak && in.jump.Value == "" {
+		in.jump = nil
+	}
+	return expressionResult, err
+}
+
+// runStatementJump runs `break` and `continue`, the loops they jump out of
+// take them
+func (in *Interpreter) runStatementJump(n *Node) (expressionResult Value, err error) {
+	in.jump = n
+	return Value{}, nil
+}
+
+// runFunction declares the function, it can be called from now on
+func (in *Interpreter) runFunction(n *Node) (expressionResult Value, err error) {
+	in.functions[n.Name] = n
+	return Value{}, nil
+}
+
+// run that ast
+func (in *Interpreter) run(n *Node) (Value, error) {
+	switch n.Kind {
+	case aExpression:
+		return in.runExpression(n)
+	case aProgram, aStatement:
+		return in.runStatement(n)
+	case aAssignmentStatement:
+		return in.runAssignmentStatement(n)
+	case aCompoundIndexAssignment:
+		return in.runCompoundIndexAssignment(n)
+	case aStatementIf:
+		return in.runStatementIf(n)
+	case aStatementWhile:
+		return in.runStatementWhile(n)
+	case aStatementFor:
+		return in.runStatementFor(n)
+	case aStatementReturn:
+		return in.runStatementReturn(n)
+	case aStatementBreak, aStatementContinue:
+		return in.runStatementJump(n)
+	case aStatementSwitch:
+		return in.runStatementSwitch(n)
+	case aFunction:
+		return in.runFunction(n)
+	case aNumberLiteral, aFloatLiteral, aStringLiteral, aBoolLiteral, aNilLiteral:
+		v, err := literalValue(n)
+		if err != nil {
+			return Value{}, in.fail(err)
+		}
+		return v, nil
+	case aListLiteral:
+		return in.runListLiteral(n)
+	case aIndexExpression:
+		return in.runIndexExpression(n)
+	case aMapLiteral:
+		return in.runMapLiteral(n)
+	case aBlank:
+		return Value{}, nil
+	}
+	return Value{}, in.fail(runtimeError(n.token, codeInternal, "can not run node of kind %d", n.Kind))
+}
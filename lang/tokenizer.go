@@ -0,0 +1,674 @@
+/**
+ * ============================================================================
+ *                                   (/^▽^)/
+ *                                THE TOKENIZER!
+ * ============================================================================
+ */
+
+package lang
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// token type iota
+const (
+	_         = iota
+	tNewLine  // \n
+	tString   // "([^"\\\n]|\\.)*"
+	tInteger  // [0-9]+
+	tFloat    // [0-9]+(\.[0-9]+)?([eE][+-]?[0-9]+)?
+	tDot      // "."
+	tComma    // ","
+	tColon    // ":"
+	tBreak    // ";"
+	tLParen   // "("
+	tRParen   // ")"
+	tLBrace   // "{"
+	tRBrace   // "}"
+	tLBracket // "["
+	tRBracket // "]"
+	// Operators and functions
+	// binary operators
+	tPlus     // "+"
+	tMinus    // "-"
+	tMultiply // "*"
+	tDivide   // "/"
+	tModulo   // "%"
+	// compare operators
+	tCalcNotEqual     // "!="
+	tCalcLessThan     // "<"
+	tCalcLessEqual    // "<="
+	tCalcGreaterThan  // ">"
+	tCalcGreaterEqual // ">="
+	tCalcEqual        // "=="
+	tEqual            // "="
+	// compound assignments
+	tPlusEqual     // "+="
+	tMinusEqual    // "-="
+	tMultiplyEqual // "*="
+	tDivideEqual   // "/="
+	tModuloEqual   // "%="
+	tIncrement     // "++"
+	tDecrement     // "--"
+	// logical operators
+	tAnd // "&&"
+	tOr  // "||"
+	tNot // "!"
+	// keywords Statement
+	tReturn     // "return"
+	tIf         // "if"
+	tElse       // "else"
+	tFor        // "for"
+	tWhile      // "while"
+	tBreakLoop  // "break", tBreak is the `;`
+	tContinue   // "continue"
+	tSwitch     // "switch"
+	tCase       // "case"
+	tDefault    // "default"
+	tPrint      // "print"
+	tIdentifier // [a-zA-Z_][a-zA-Z0-9_]*
+	// trivia
+	tComment // "//.*" or "/* */"
+)
+
+// tokenNames is used to print the kind of a token
+var tokenNames = map[int]string{
+	tNewLine:          "NewLine",
+	tString:           "String",
+	tInteger:          "Integer",
+	tFloat:            "Float",
+	tDot:              "Dot",
+	tComma:            "Comma",
+	tColon:            "Colon",
+	tBreak:            "Break",
+	tLParen:           "LParen",
+	tRParen:           "RParen",
+	tLBrace:           "LBrace",
+	tRBrace:           "RBrace",
+	tLBracket:         "LBracket",
+	tRBracket:         "RBracket",
+	tPlus:             "Plus",
+	tMinus:            "Minus",
+	tMultiply:         "Multiply",
+	tDivide:           "Divide",
+	tModulo:           "Modulo",
+	tCalcNotEqual:     "NotEqual",
+	tCalcLessThan:     "LessThan",
+	tCalcLessEqual:    "LessEqual",
+	tCalcGreaterThan:  "GreaterThan",
+	tCalcGreaterEqual: "GreaterEqual",
+	tCalcEqual:        "Equal",
+	tEqual:            "Assign",
+	tPlusEqual:        "PlusAssign",
+	tMinusEqual:       "MinusAssign",
+	tMultiplyEqual:    "MultiplyAssign",
+	tDivideEqual:      "DivideAssign",
+	tModuloEqual:      "ModuloAssign",
+	tIncrement:        "Increment",
+	tDecrement:        "Decrement",
+	tAnd:              "And",
+	tOr:               "Or",
+	tNot:              "Not",
+	tReturn:           "Return",
+	tIf:               "If",
+	tElse:             "Else",
+	tFor:              "For",
+	tWhile:            "While",
+	tBreakLoop:        "BreakLoop",
+	tContinue:         "Continue",
+	tSwitch:           "Switch",
+	tCase:             "Case",
+	tDefault:          "Default",
+	tPrint:            "Print",
+	tIdentifier:       "Identifier",
+	tComment:          "Comment",
+}
+
+// keywords map[word]kind, a word is lexed as a whole before it is looked up
+// here, so `iffy` stays an identifier
+var keywords = map[string]int{
+	"return":   tReturn,
+	"if":       tIf,
+	"else":     tElse,
+	"for":      tFor,
+	"while":    tWhile,
+	"break":    tBreakLoop,
+	"continue": tContinue,
+	"switch":   tSwitch,
+	"case":     tCase,
+	"default":  tDefault,
+	"print":    tPrint,
+}
+
+// Token is one lexeme of the source, line and col are where it starts
+type Token struct {
+	kind  int
+	value string
+	line  int
+	col   int
+}
+
+// String prints the token as `line:col kind value`
+func (t Token) String() string {
+	return fmt.Sprintf("%d:%d\t%s\t%s", t.line, t.col, tokenNames[t.kind], t.value)
+}
+
+// Tokenize lex and tokenize the content, the comments are dropped. The
+// errors are an ErrorList, the tokens are there even when there are errors
+// so that the parser can look for its own ones.
+func Tokenize(content []byte) (tokens []Token, err error) {
+	tokens, err = TokenizeTrivia(content)
+	return stripTrivia(tokens), err
+}
+
+// stripTrivia drops the comments of the tokens, the parser does not want them
+func stripTrivia(tokens []Token) []Token {
+	code := make([]Token, 0, len(tokens))
+	for _, t := range tokens {
+		if t.kind != tComment {
+			code = append(code, t)
+		}
+	}
+	return code
+}
+
+// TokenizeTrivia lex and tokenize the content like Tokenize, but keeps the
+// comments as tComment tokens right before the token they come before, so
+// the formatter can write them back. A character that is not valid is
+// reported and left out, the rest of the content is lexed all the same.
+func TokenizeTrivia(content []byte) (tokens []Token, err error) {
+	tokens = make([]Token, len(content))
+	var errs ErrorList
+	i := 0
+	line := 1
+	col := 1
+	for currPos := 0; currPos < len(content); currPos++ {
+		switch {
+		// "\n"                    SAVE_TOKEN; return tNewLine;
+		case content[currPos] == '\n':
+			if currPos > 0 && content[currPos-1] == '\r' {
+				tokens[i] = Token{tNewLine, "\\n", line, col - 1}
+				i++
+			} else {
+				tokens[i] = Token{tNewLine, "\\n", line, col}
+				i++
+			}
+			line++
+			col = 1
+			break
+
+		// '\"'                     SAVE_TOKEN; return tString;
+		case content[currPos] == '"':
+			// the value is the source of the string with both quotes, the
+			// parser decodes the escapes. A string that is not closed goes to
+			// the end of the line.
+			targetPos, err := readString(content, currPos, line, col)
+			if err != nil {
+				errs = append(errs, err)
+			}
+			tokens[i] = Token{tString, string(content[currPos:targetPos]), line, col}
+			i++
+			col = col + targetPos - currPos
+			currPos = targetPos - 1
+			break
+
+		// blank				   Skip;
+		case content[currPos] == ' ' || content[currPos] == '\t' || content[currPos] == '\r':
+			col++
+			break
+
+		// [0-9]+                  SAVE_TOKEN; return tInteger;
+		// [0-9]+(\.[0-9]+)?([eE][+-]?[0-9]+)?
+		//                         SAVE_TOKEN; return tFloat;
+		case (content[currPos] >= '0') && (content[currPos] <= '9'):
+			// store the number string to value
+			// tokens[i] = token{TINTEGER, value, line, col}
+			tokens[i] = Token{tInteger, "", line, col}
+			targetPos := skipDigits(content, currPos+1)
+			// the fraction, `3.` and `3.a` stay an integer and a dot
+			if targetPos+1 < len(content) && content[targetPos] == '.' && isDigit(content[targetPos+1]) {
+				tokens[i].kind = tFloat
+				targetPos = skipDigits(content, targetPos+1)
+			}
+			// the exponent, `2e` stays an integer and an identifier
+			if targetPos < len(content) && (content[targetPos] == 'e' || content[targetPos] == 'E') {
+				exponent := targetPos + 1
+				if exponent < len(content) && (content[exponent] == '+' || content[exponent] == '-') {
+					exponent++
+				}
+				if exponent < len(content) && isDigit(content[exponent]) {
+					tokens[i].kind = tFloat
+					targetPos = skipDigits(content, exponent)
+				}
+			}
+			tokens[i].value = string(content[currPos:targetPos])
+			i++
+			col = col + targetPos - currPos
+			currPos = targetPos - 1
+			break
+
+		// "."                     return TOKEN(tDot);
+		case content[currPos] == '.':
+			tokens[i] = Token{tDot, ".", line, col}
+			i++
+			col++
+			break
+
+		// ","                     return TOKEN(tComma);
+		case content[currPos] == ',':
+			tokens[i] = Token{tComma, ",", line, col}
+			i++
+			col++
+			break
+
+		// ":"                     return TOKEN(tColon);
+		case content[currPos] == ':':
+			tokens[i] = Token{tColon, ":", line, col}
+			i++
+			col++
+			break
+
+		// ";"                     return TOKEN(tBreak);
+		case content[currPos] == ';':
+			tokens[i] = Token{tBreak, ";", line, col}
+			i++
+			col++
+			break
+
+		// "+"                     return TOKEN(tPlus);
+		// "+="                    return TOKEN(tPlusEqual);
+		// "++"                    return TOKEN(tIncrement);
+		case content[currPos] == '+':
+			tokens[i] = operatorToken(content, currPos, tPlus, tPlusEqual, tIncrement, line, col)
+			col += len(tokens[i].value)
+			currPos += len(tokens[i].value) - 1
+			i++
+			break
+
+		// "-"                     return TOKEN(tMinus);
+		// "-="                    return TOKEN(tMinusEqual);
+		// "--"                    return TOKEN(tDecrement);
+		case content[currPos] == '-':
+			tokens[i] = operatorToken(content, currPos, tMinus, tMinusEqual, tDecrement, line, col)
+			col += len(tokens[i].value)
+			currPos += len(tokens[i].value) - 1
+			i++
+			break
+
+		// "*"                     return TOKEN(tMultiple);
+		// "*="                    return TOKEN(tMultiplyEqual);
+		case content[currPos] == '*':
+			tokens[i] = operatorToken(content, currPos, tMultiply, tMultiplyEqual, 0, line, col)
+			col += len(tokens[i].value)
+			currPos += len(tokens[i].value) - 1
+			i++
+			break
+
+		// "%"                     return TOKEN(tModulo);
+		// "%="                    return TOKEN(tModuloEqual);
+		case content[currPos] == '%':
+			tokens[i] = operatorToken(content, currPos, tModulo, tModuloEqual, 0, line, col)
+			col += len(tokens[i].value)
+			currPos += len(tokens[i].value) - 1
+			i++
+			break
+
+		// "//".*                  SAVE_TOKEN; return tComment;
+		case content[currPos] == '/' && currPos+1 < len(content) && content[currPos+1] == '/':
+			// read to the end of the line, the "\n" is still a tNewLine
+			targetPos := currPos + 2
+			for targetPos < len(content) && content[targetPos] != '\n' && content[targetPos] != '\r' {
+				targetPos++
+			}
+			tokens[i] = Token{tComment, string(content[currPos:targetPos]), line, col}
+			i++
+			col = col + targetPos - currPos
+			currPos = targetPos - 1
+			break
+
+		// "/*" ... "*/"           SAVE_TOKEN; return tComment;
+		case content[currPos] == '/' && currPos+1 < len(content) && content[currPos+1] == '*':
+			t := Token{tComment, "", line, col}
+			targetPos := currPos + 2
+			col += 2
+			// a nested comment is reported and closed by its own `*/`, so the
+			// rest of the content is lexed as it is meant to be
+			nested := 0
+			for {
+				if targetPos+1 >= len(content) {
+					errs = append(errs, syntaxError(Token{value: "/*", line: t.line, col: t.col}, codeUnterminatedComment, "unterminated block comment"))
+					targetPos = len(content)
+					break
+				}
+				if content[targetPos] == '*' && content[targetPos+1] == '/' {
+					targetPos += 2
+					col += 2
+					if nested == 0 {
+						break
+					}
+					nested--
+					continue
+				}
+				if content[targetPos] == '/' && content[targetPos+1] == '*' {
+					err := syntaxError(Token{value: "/*", line: line, col: col}, codeNestedComment, "nested block comment")
+					err.Notes = []string{fmt.Sprintf("the comment is opened at %d:%d", t.line, t.col)}
+					errs = append(errs, err)
+					nested++
+					targetPos += 2
+					col += 2
+					continue
+				}
+				if content[targetPos] == '\n' {
+					line++
+					col = 1
+				} else {
+					col++
+				}
+				targetPos++
+			}
+			t.value = string(content[currPos:targetPos])
+			tokens[i] = t
+			i++
+			currPos = targetPos - 1
+			break
+
+		// "/"                     return TOKEN(tDivide);
+		// "/="                    return TOKEN(tDivideEqual);
+		case content[currPos] == '/':
+			tokens[i] = operatorToken(content, currPos, tDivide, tDivideEqual, 0, line, col)
+			col += len(tokens[i].value)
+			currPos += len(tokens[i].value) - 1
+			i++
+			break
+
+		// "("                     return TOKEN(tLParen);
+		case content[currPos] == '(':
+			tokens[i] = Token{tLParen, "(", line, col}
+			i++
+			col++
+			break
+
+		// ")"                     return TOKEN(tRParen);
+		case content[currPos] == ')':
+			tokens[i] = Token{tRParen, ")", line, col}
+			i++
+			col++
+			break
+
+		// "{"                     return TOKEN(tLBrace);
+		case content[currPos] == '{':
+			tokens[i] = Token{tLBrace, "{", line, col}
+			i++
+			col++
+			break
+
+		// "}"                     return TOKEN(tRBrace);
+		case content[currPos] == '}':
+			tokens[i] = Token{tRBrace, "}", line, col}
+			i++
+			col++
+			break
+
+		// "["                     return TOKEN(tLBracket);
+		case content[currPos] == '[':
+			tokens[i] = Token{tLBracket, "[", line, col}
+			i++
+			col++
+			break
+
+		// "]"                     return TOKEN(tRBracket);
+		case content[currPos] == ']':
+			tokens[i] = Token{tRBracket, "]", line, col}
+			i++
+			col++
+			break
+
+		//"!="                    return TOKEN(tCalcNotEqual);
+		//"!"                     return TOKEN(tNot);
+		case content[currPos] == '!':
+			if currPos+1 < len(content) && content[currPos+1] == '=' {
+				tokens[i] = Token{tCalcNotEqual, "!=", line, col}
+				i++
+				col = col + 2
+				currPos++
+			} else {
+				tokens[i] = Token{tNot, "!", line, col}
+				i++
+				col++
+			}
+			break
+
+		//"&&"                    return TOKEN(tAnd);
+		//"||"                    return TOKEN(tOr);
+		case content[currPos] == '&' || content[currPos] == '|':
+			c := content[currPos]
+			if currPos+1 >= len(content) || content[currPos+1] != c {
+				err := syntaxError(Token{value: string(c), line: line, col: col}, codeInvalidCharacter, "invalid character %q", c)
+				err.Notes = []string{fmt.Sprintf("the logical operator is `%c%c`", c, c)}
+				errs = append(errs, err)
+				col++
+				break
+			}
+			kind := tAnd
+			if c == '|' {
+				kind = tOr
+			}
+			tokens[i] = Token{kind, string(content[currPos : currPos+2]), line, col}
+			i++
+			col = col + 2
+			currPos++
+			break
+
+		//"<"                     return TOKEN(tCalcLessThan);
+		//"<="                    return TOKEN(tCalcLessEqual);
+		case content[currPos] == '<':
+			if currPos+1 < len(content) && content[currPos+1] == '=' {
+				tokens[i] = Token{tCalcLessEqual, "<=", line, col}
+				i++
+				col = col + 2
+				currPos++
+			} else {
+				tokens[i] = Token{tCalcLessThan, "<", line, col}
+				i++
+				col++
+			}
+			break
+
+		//">"                     return TOKEN(tCalcGreaterThan);
+		//">="                    return TOKEN(tCalcGreaterEqual);
+		case content[currPos] == '>':
+			if currPos+1 < len(content) && content[currPos+1] == '=' {
+				tokens[i] = Token{tCalcGreaterEqual, ">=", line, col}
+				i++
+				col = col + 2
+				currPos++
+			} else {
+				tokens[i] = Token{tCalcGreaterThan, ">", line, col}
+				i++
+				col++
+			}
+			break
+
+		//"=="                    return TOKEN(tCalcEqual);
+		//"="                     return TOKEN(tEqual);
+		case content[currPos] == '=':
+			if currPos+1 < len(content) && content[currPos+1] == '=' {
+				tokens[i] = Token{tCalcEqual, "==", line, col}
+				i++
+				col = col + 2
+				currPos = currPos + 1
+			} else {
+				tokens[i] = Token{tEqual, "=", line, col}
+				i++
+				col++
+			}
+			break
+
+		//	[a-zA-Z_][a-zA-Z0-9_]*  SAVE_TOKEN; return tIdentifier;
+		//	                        or the kind of the keyword
+		case content[currPos] >= 'a' && content[currPos] <= 'z' || content[currPos] >= 'A' && content[currPos] <= 'Z' || content[currPos] == '_':
+			targetPos := currPos + 1
+			for targetPos < len(content) && ((content[targetPos] >= 'a' && content[targetPos] <= 'z') || (content[targetPos] >= 'A' && content[targetPos] <= 'Z') || (content[targetPos] == '_') || (content[targetPos] >= '0' && content[targetPos] <= '9')) {
+				targetPos++
+			}
+			value := string(content[currPos:targetPos])
+			kind, ok := keywords[value]
+			if !ok {
+				kind = tIdentifier
+			}
+			tokens[i] = Token{kind, value, line, col}
+			i++
+			col = col + targetPos - currPos
+			currPos = targetPos - 1
+			break
+
+		default:
+			r, size := utf8.DecodeRune(content[currPos:])
+			errs = append(errs, syntaxError(Token{value: string(r), line: line, col: col}, codeInvalidCharacter, "invalid character %q", r))
+			col++
+			currPos += size - 1
+			break
+		}
+	}
+	if len(errs) > 0 {
+		return tokens[0:i], errs
+	}
+	return tokens[0:i], nil
+}
+
+// operatorToken lexes the operator at pos that is op alone, withEqual when
+// a `=` follows and doubled when the same character follows, 0 if it can not
+// be doubled
+func operatorToken(content []byte, pos int, op int, withEqual int, doubled int, line int, col int) Token {
+	c := content[pos]
+	if pos+1 < len(content) {
+		switch {
+		case content[pos+1] == '=':
+			return Token{withEqual, string([]byte{c, '='}), line, col}
+		case content[pos+1] == c && doubled != 0:
+			return Token{doubled, string([]byte{c, c}), line, col}
+		}
+	}
+	return Token{op, string(c), line, col}
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// skipDigits gives back the position of the first non digit from pos on
+func skipDigits(content []byte, pos int) int {
+	for pos < len(content) && isDigit(content[pos]) {
+		pos++
+	}
+	return pos
+}
+
+// readString reads the string literal that starts with the `"` at start, it
+// gives back the position after the closing `"`. line and col are where start
+// is, for the errors, the position is the end of the line for a string that
+// is not closed. decodeString works out its value later on.
+func readString(content []byte, start int, line int, col int) (int, *SyntaxError) {
+	var first *SyntaxError
+	pos := start + 1
+	for {
+		if pos >= len(content) || content[pos] == '\n' {
+			err := syntaxError(Token{value: "\"", line: line, col: col}, codeUnterminatedString, "unterminated string")
+			err.Notes = []string{"a string ends on the line it starts, use \\n for a new line"}
+			return pos, err
+		}
+		switch content[pos] {
+		case '"':
+			return pos + 1, first
+		case '\\':
+			// the escape sequence, its errors are at the backslash
+			_, end, err := readEscape(content, pos, Token{value: "\\", line: line, col: col + pos - start})
+			if err != nil && first == nil {
+				first = err
+			}
+			pos = end
+		default:
+			pos++
+		}
+	}
+}
+
+// decodeString gives back the value of the string literal read by
+// readString, with the escapes decoded. An escape that is not valid stays
+// as it is, the tokenizer reported it already.
+//
+//	\n \t \r \" \\     a new line, a tab, a carriage return, a quote, a backslash
+//	\u{1F600}        the unicode character of 1 to 6 hex digits
+func decodeString(literal string) string {
+	content := []byte(literal)
+	var b strings.Builder
+	// the escapes are taken as a whole, so a `"` is the closing one
+	for pos := 1; pos < len(content) && content[pos] != '"'; {
+		if content[pos] != '\\' {
+			b.WriteByte(content[pos])
+			pos++
+			continue
+		}
+		value, end, err := readEscape(content, pos, Token{})
+		if err != nil {
+			value = string(content[pos:end])
+		}
+		b.WriteString(value)
+		pos = end
+	}
+	return b.String()
+}
+
+// readEscape decodes the escape sequence of the backslash at pos, it gives
+// back its value and the position after it. The errors are at the token at.
+func readEscape(content []byte, pos int, at Token) (string, int, *SyntaxError) {
+	if pos+1 >= len(content) || content[pos+1] == '\n' {
+		// a string can not go on to the next line
+		return "", pos + 1, nil
+	}
+	switch content[pos+1] {
+	case 'n':
+		return "\n", pos + 2, nil
+	case 't':
+		return "\t", pos + 2, nil
+	case 'r':
+		return "\r", pos + 2, nil
+	case '"':
+		return "\"", pos + 2, nil
+	case '\\':
+		return "\\", pos + 2, nil
+	case 'u':
+		end := pos + 2
+		if end < len(content) && content[end] == '{' {
+			end++
+			for end < len(content) && end-pos-3 < 7 && isHexDigit(content[end]) {
+				end++
+			}
+		}
+		digits := ""
+		if end < len(content) && content[end] == '}' {
+			digits = string(content[pos+3 : end])
+		}
+		r, err := strconv.ParseUint(digits, 16, 32)
+		if len(digits) == 0 || len(digits) > 6 || err != nil {
+			at.value = "\\u"
+			return "", pos + 2, syntaxError(at, codeInvalidEscape, "invalid unicode escape, should be like \\u{1F600}")
+		}
+		if !utf8.ValidRune(rune(r)) {
+			at.value = string(content[pos : end+1])
+			return "", end + 1, syntaxError(at, codeInvalidEscape, "invalid unicode character %s", at.value)
+		}
+		return string(rune(r)), end + 1, nil
+	}
+	r, size := utf8.DecodeRune(content[pos+1:])
+	at.value = "\\" + string(r)
+	return "", pos + 1 + size, syntaxError(at, codeInvalidEscape, "invalid escape sequence %s", at.value)
+}
+
+func isHexDigit(c byte) bool {
+	return isDigit(c) || c >= 'a' && c <= 'f' || c >= 'A' && c <= 'F'
+}
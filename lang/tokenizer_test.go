@@ -0,0 +1,198 @@
+package lang
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// kinds prints the kinds of the tokens separated by blanks
+func kinds(tokens []Token) string {
+	names := make([]string, len(tokens))
+	for i, t := range tokens {
+		names[i] = tokenNames[t.kind]
+	}
+	return strings.Join(names, " ")
+}
+
+// TestTokenizerKinds checks the kinds of the tokens every part of the
+// tokenizer gives back
+func TestTokenizerKinds(t *testing.T) {
+	tests := []struct {
+		source string
+		want   string
+	}{
+		// keywords are whole words
+		{"if else for while return", "If Else For While Return"},
+		{"break continue; breaks", "BreakLoop Continue Break Identifier"},
+		{"switch case default defaults", "Switch Case Default Identifier"},
+		{"switch case default defaults", "Switch Case Default Identifier"},
+		{"iffy format elsewhere whiles returned", "Identifier Identifier Identifier Identifier Identifier"},
+		{"if_ for1 _while", "Identifier Identifier Identifier"},
+		{"i", "Identifier"},
+		{"retur", "Identifier"},
+		// new lines, CRLF is one of them
+		{"a = b\nif", "Identifier Assign Identifier NewLine If"},
+		{"a = b\r\nif\r\n", "Identifier Assign Identifier NewLine If NewLine"},
+		{"\r\n\r\nwhile", "NewLine NewLine While"},
+		// numbers
+		{"3 3.14 1e-3 2.5E+2 10e3", "Integer Float Float Float Float"},
+		{"3.", "Integer Dot"},
+		{"3.a", "Integer Dot Identifier"},
+		{"2e", "Integer Identifier"},
+		{"2e+", "Integer Identifier Plus"},
+		{"1.5.2", "Float Dot Integer"},
+		// arithmetic operators and compound assignments
+		{"a % b", "Identifier Modulo Identifier"},
+		{"a += 1 -= *= /= %=", "Identifier PlusAssign Integer MinusAssign MultiplyAssign DivideAssign ModuloAssign"},
+		{"i++ i-- +++", "Identifier Increment Identifier Decrement Increment Plus"},
+		{"a=-5", "Identifier Assign Minus Integer"},
+		{"a - -b", "Identifier Minus Minus Identifier"},
+		{"a/=2//c", "Identifier DivideAssign Integer"},
+		// logical operators
+		{"!a != b && c || !d", "Not Identifier NotEqual Identifier And Identifier Or Not Identifier"},
+		// lists and maps
+		{"a[0] = [1]", "Identifier LBracket Integer RBracket Assign LBracket Integer RBracket"},
+		{"[]", "LBracket RBracket"},
+		{"a[b[1]]", "Identifier LBracket Identifier LBracket Integer RBracket RBracket"},
+		{"{\"k\": 1}", "LBrace String Colon Integer RBrace"},
+		{"m[\"k\"]:", "Identifier LBracket String RBracket Colon"},
+		// comments are dropped
+		{"a = 1 // one", "Identifier Assign Integer"},
+		{"// only a comment\na", "NewLine Identifier"},
+		{"a / b", "Identifier Divide Identifier"},
+		{"a /* b */ / c", "Identifier Divide Identifier"},
+		{"/* one\ntwo */ a", "Identifier"},
+		{"a = 1 /**/", "Identifier Assign Integer"},
+	}
+	for _, tt := range tests {
+		tokens, err := Tokenize([]byte(tt.source))
+		if err != nil {
+			t.Errorf("Tokenize(%q): unexpected error %v", tt.source, err)
+			continue
+		}
+		if got := kinds(tokens); got != tt.want {
+			t.Errorf("Tokenize(%q) = %s, want %s", tt.source, got, tt.want)
+		}
+	}
+}
+
+func TestTokenizerTrivia(t *testing.T) {
+	tokens, err := TokenizeTrivia([]byte("a = 1 // one\n/* two\n */ b"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Token{
+		{tIdentifier, "a", 1, 1},
+		{tEqual, "=", 1, 3},
+		{tInteger, "1", 1, 5},
+		{tComment, "// one", 1, 7},
+		{tNewLine, "\\n", 1, 13},
+		{tComment, "/* two\n */", 2, 1},
+		{tIdentifier, "b", 3, 5},
+	}
+	if len(tokens) != len(want) {
+		t.Fatalf("TokenizeTrivia gave %v, want %v", tokens, want)
+	}
+	for i := range want {
+		if tokens[i] != want[i] {
+			t.Errorf("token %d is %v, want %v", i, tokens[i], want[i])
+		}
+	}
+}
+
+func TestTokenizerStrings(t *testing.T) {
+	tests := []struct {
+		source string
+		want   string
+	}{
+		{`""`, ""},
+		{`"a b"`, "a b"},
+		{`"a\nb\tc\r"`, "a\nb\tc\r"},
+		{`"say \"hi\""`, `say "hi"`},
+		{`"back\\slash\\"`, `back\slash\`},
+		{`"caf\u{e9} \u{1F600}"`, "café 😀"},
+		{`"héllo"`, "héllo"},
+	}
+	for _, tt := range tests {
+		tokens, err := Tokenize([]byte(tt.source + " a"))
+		if err != nil {
+			t.Errorf("Tokenize(%q): unexpected error %v", tt.source, err)
+			continue
+		}
+		// the token keeps the source, the closing quote too
+		if len(tokens) != 2 || tokens[0].value != tt.source || tokens[1].col != len(tt.source)+2 {
+			t.Errorf("Tokenize(%q) = %v", tt.source, tokens)
+			continue
+		}
+		ast, err := Parse(tokens[:1])
+		if err != nil {
+			t.Errorf("Parse(%q): unexpected error %v", tt.source, err)
+			continue
+		}
+		if got := ast.Body[0].Value; got != tt.want {
+			t.Errorf("the value of %s is %q, want %q", tt.source, got, tt.want)
+		}
+	}
+}
+
+// TestTokenizerErrors checks the errors of the tokenizer, all of them are
+// in the ErrorList
+func TestTokenizerErrors(t *testing.T) {
+	tests := []struct {
+		source string
+		want   string
+	}{
+		// comments
+		{"a = 1 /* one", "unterminated block comment at line 1, col 7"},
+		{"/*", "unterminated block comment at line 1, col 1"},
+		{"a\n/* one /* two */ */", "nested block comment at line 2, col 8"},
+		// strings
+		{`a = "one`, "unterminated string at line 1, col 5"},
+		{"a = \"one\ntwo\"", "unterminated string at line 1, col 5\nunterminated string at line 2, col 4"},
+		{`a = "one\`, "unterminated string at line 1, col 5"},
+		{`a = "one\q"`, `invalid escape sequence \q at line 1, col 9`},
+		{`"\u00e9"`, `invalid unicode escape, should be like \u{1F600} at line 1, col 2`},
+		{`"\u{}"`, `invalid unicode escape, should be like \u{1F600} at line 1, col 2`},
+		{`"\u{1234567}"`, `invalid unicode escape, should be like \u{1F600} at line 1, col 2`},
+		{`"ok" "\u{D800}"`, `invalid unicode character \u{D800} at line 1, col 7`},
+		// a single `&` or `|`
+		{"a & b", "invalid character '&' at line 1, col 3"},
+		{"a | b", "invalid character '|' at line 1, col 3"},
+		{"a &", "invalid character '&' at line 1, col 3"},
+		// characters
+		{"a = 1 $", "invalid character '$' at line 1, col 7"},
+		{"a = 1 $ 2 é", "invalid character '$' at line 1, col 7\ninvalid character 'é' at line 1, col 11"},
+	}
+	for _, tt := range tests {
+		_, err := Tokenize([]byte(tt.source))
+		if err == nil || err.Error() != tt.want {
+			t.Errorf("Tokenize(%q) gave error %v, want %s", tt.source, err, tt.want)
+		}
+	}
+}
+
+func TestTokenizerRecovery(t *testing.T) {
+	tests := []struct {
+		source string
+		want   string
+		errors int
+	}{
+		{"a = 1 $ 2 # 3", "Identifier Assign Integer Integer Integer", 2},
+		{"a & b | c", "Identifier Identifier Identifier", 2},
+		{"a = \"b\\q\" + c", "Identifier Assign String Plus Identifier", 1},
+		{"a = \"b\nc", "Identifier Assign String NewLine Identifier", 1},
+		{"/* a /* b */ c */ d", "Comment Identifier", 1},
+		{"a /* b", "Identifier Comment", 1},
+	}
+	for _, tt := range tests {
+		tokens, err := TokenizeTrivia([]byte(tt.source))
+		var list ErrorList
+		if !errors.As(err, &list) || len(list) != tt.errors {
+			t.Errorf("TokenizeTrivia(%q) gave errors %v, want %d of them", tt.source, err, tt.errors)
+		}
+		if got := kinds(tokens); got != tt.want {
+			t.Errorf("TokenizeTrivia(%q) = %s, want %s", tt.source, got, tt.want)
+		}
+	}
+}
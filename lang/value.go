@@ -0,0 +1,645 @@
+/**
+ * ============================================================================
+ *                                   (づ｡◕‿◕｡)づ
+ *                                 THE VALUES
+ * ============================================================================
+ */
+
+package lang
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+/*
+Every expression gives back a Value at runtime. Both backends share them, and
+they share the rules for working with them, nothing is converted behind the
+back of the program:
+
+  + - * / %   int with int gives an int, a float with an int or a float
+              gives a float, the int is promoted. % is the remainder, it has
+              the sign of the left side
+  - +         in front of a number, - negates it
+  + on strings joins them, a string is never joined with a value of another
+              kind, str() it first
+  < <= > >=   two numbers or two strings give a bool, the strings are
+              compared byte by byte
+  == !=       any two values, an int and a float are compared as floats,
+              two lists item by item, two maps entry by entry, and other
+              values of different kinds are never equal
+  && || !     any values, they are true or false like in an if and give a
+              bool, the right side of && and || only runs when it is needed
+  a[i]        the item i of the list a, from 0 to len(a) - 1
+  m[k]        the value of the key k of the map m, it has to be there
+  if, while   false, nil, 0, 0.0, "", [] and {} are false, everything else
+              is true
+
+A float is printed with a fraction, so 3.0 does not look like the int 3.
+
+A list or a map is shared, not copied: after `b = a` both are the same list,
+and push(b, 1) changes a too.
+
+The keys of a map are ints, floats, strings and bools, a float that is a whole
+number is the same key as the int. A map keeps its keys in the order they were
+added, so printing it and keys() always give the same order.
+
+Everything else is a RuntimeError caused by a TypeError.
+*/
+
+// kinds of the runtime values
+const (
+	vNil = iota
+	vInt
+	vString
+	vBool
+	vFloat
+	vList
+	vMap
+)
+
+// valueKindNames is used in the error messages
+var valueKindNames = map[int]string{
+	vNil:    "nil",
+	vInt:    "int",
+	vString: "string",
+	vBool:   "bool",
+	vFloat:  "float",
+	vList:   "list",
+	vMap:    "map",
+}
+
+// Value is a runtime value, the zero Value is nil
+type Value struct {
+	kind int
+	// num is the vInt, or the vBool as 0 and 1
+	num int
+	flt float64
+	str string
+	// list and dict are shared by all the copies of the value
+	list *list
+	dict *dict
+}
+
+// list are the items of a vList
+type list struct {
+	items []Value
+}
+
+// dict are the entries of a vMap, keys are in the order they were added
+type dict struct {
+	keys   []Value
+	values map[Value]Value
+}
+
+func newDict() *dict {
+	return &dict{values: make(map[Value]Value)}
+}
+
+// set adds or replaces the entry of key
+func (d *dict) set(key Value, v Value) {
+	if _, ok := d.values[key]; !ok {
+		d.keys = append(d.keys, key)
+	}
+	d.values[key] = v
+}
+
+// remove drops the entry of key, if there is one
+func (d *dict) remove(key Value) {
+	if _, ok := d.values[key]; !ok {
+		return
+	}
+	delete(d.values, key)
+	for i, k := range d.keys {
+		if k == key {
+			d.keys = append(d.keys[:i], d.keys[i+1:]...)
+			break
+		}
+	}
+}
+
+// IntValue, FloatValue, StringValue and BoolValue build the values a host
+// function gives back to the program
+func IntValue(i int) Value {
+	return Value{kind: vInt, num: i}
+}
+
+func FloatValue(f float64) Value {
+	return Value{kind: vFloat, flt: f}
+}
+
+func StringValue(s string) Value {
+	return Value{kind: vString, str: s}
+}
+
+func BoolValue(b bool) Value {
+	if b {
+		return Value{kind: vBool, num: 1}
+	}
+	return Value{kind: vBool}
+}
+
+// ListValue builds a new list of the items
+func ListValue(items ...Value) Value {
+	return Value{kind: vList, list: &list{append([]Value{}, items...)}}
+}
+
+// MapValue builds a new map of the entries, the keys are added in sorted order
+func MapValue(entries map[string]Value) Value {
+	keys := make([]string, 0, len(entries))
+	for k := range entries {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	d := newDict()
+	for _, k := range keys {
+		d.set(StringValue(k), entries[k])
+	}
+	return Value{kind: vMap, dict: d}
+}
+
+// String formats the value the way print shows it
+func (v Value) String() string {
+	return v.format(nil)
+}
+
+// format writes the value, seen are the lists and maps it is in so that a
+// list that contains itself is written as [...]
+func (v Value) format(seen []interface{}) string {
+	switch v.kind {
+	case vList:
+		for _, s := range seen {
+			if s == v.list {
+				return "[...]"
+			}
+		}
+		seen = append(seen, v.list)
+		items := make([]string, len(v.list.items))
+		for i, item := range v.list.items {
+			items[i] = item.formatItem(seen)
+		}
+		return "[" + strings.Join(items, ", ") + "]"
+	case vMap:
+		for _, s := range seen {
+			if s == v.dict {
+				return "{...}"
+			}
+		}
+		seen = append(seen, v.dict)
+		entries := make([]string, len(v.dict.keys))
+		for i, k := range v.dict.keys {
+			entries[i] = k.formatItem(seen) + ": " + v.dict.values[k].formatItem(seen)
+		}
+		return "{" + strings.Join(entries, ", ") + "}"
+	case vInt:
+		return strconv.Itoa(v.num)
+	case vFloat:
+		return formatFloat(v.flt)
+	case vString:
+		return v.str
+	case vBool:
+		if v.num != 0 {
+			return "true"
+		}
+		return "false"
+	}
+	return "nil"
+}
+
+// formatFloat writes the float with a fraction or an exponent, never like an
+// int
+func formatFloat(f float64) string {
+	if math.IsInf(f, 0) || math.IsNaN(f) {
+		return strconv.FormatFloat(f, 'g', -1, 64)
+	}
+	if a := math.Abs(f); a != 0 && (a < 1e-4 || a >= 1e21) {
+		return strconv.FormatFloat(f, 'g', -1, 64)
+	}
+	s := strconv.FormatFloat(f, 'f', -1, 64)
+	if !strings.Contains(s, ".") {
+		s += ".0"
+	}
+	return s
+}
+
+// formatItem writes a value inside of a list or a map, the strings are quoted
+func (v Value) formatItem(seen []interface{}) string {
+	if v.kind == vString {
+		return quote(v.str)
+	}
+	return v.format(seen)
+}
+
+// quote writes the string the way it is written in the source
+func quote(s string) string {
+	return "\"" + strings.NewReplacer("\\", "\\\\", "\"", "\\\"", "\n", "\\n", "\t", "\\t", "\r", "\\r").Replace(s) + "\""
+}
+
+// Type is the name of the kind of the value: nil, int, float, string, bool,
+// list or map
+func (v Value) Type() string {
+	return valueKindNames[v.kind]
+}
+
+// IsNil tells whether the value is nil
+func (v Value) IsNil() bool {
+	return v.kind == vNil
+}
+
+// Int gives back the int of the value, ok is false if it is not an int
+func (v Value) Int() (i int, ok bool) {
+	return v.num, v.kind == vInt
+}
+
+// Float gives back the float of the value, ok is false if it is not a float.
+// An int is not converted.
+func (v Value) Float() (f float64, ok bool) {
+	return v.flt, v.kind == vFloat
+}
+
+// List gives back the items of the list, ok is false if it is not a list.
+// The items are shared with the program.
+func (v Value) List() (items []Value, ok bool) {
+	if v.kind != vList {
+		return nil, false
+	}
+	return v.list.items, true
+}
+
+// Map gives back the keys of the map in order and the values of the keys,
+// ok is false if it is not a map
+func (v Value) Map() (keys []Value, values []Value, ok bool) {
+	if v.kind != vMap {
+		return nil, nil, false
+	}
+	keys = append([]Value{}, v.dict.keys...)
+	values = make([]Value, len(keys))
+	for i, k := range keys {
+		values[i] = v.dict.values[k]
+	}
+	return keys, values, true
+}
+
+// Str gives back the string of the value, ok is false if it is not a string.
+// Use String to format any value.
+func (v Value) Str() (s string, ok bool) {
+	return v.str, v.kind == vString
+}
+
+// Bool gives back the bool of the value, ok is false if it is not a bool
+func (v Value) Bool() (b bool, ok bool) {
+	return v.num != 0, v.kind == vBool
+}
+
+// truthy decides which way an if or a loop goes
+func (v Value) truthy() bool {
+	switch v.kind {
+	case vInt, vBool:
+		return v.num != 0
+	case vFloat:
+		return v.flt != 0
+	case vString:
+		return v.str != ""
+	case vList:
+		return len(v.list.items) > 0
+	case vMap:
+		return len(v.dict.keys) > 0
+	}
+	return false
+}
+
+// TypeError is the cause of a RuntimeError when a value has the wrong kind
+// for what the program does with it, t is the token that did it
+type TypeError struct {
+	token   Token
+	message string
+}
+
+func (e *TypeError) Error() string {
+	return fmt.Sprintf("type error: %s at line %d, col %d", e.message, e.token.line, e.token.col)
+}
+
+// typeError builds the RuntimeError of a TypeError
+func typeError(t Token, format string, args ...interface{}) *RuntimeError {
+	cause := &TypeError{t, fmt.Sprintf(format, args...)}
+	return &RuntimeError{token: t, code: codeTypeError, message: cause.message, err: cause}
+}
+
+// literalValue gives back the value of a literal node
+func literalValue(n *Node) (Value, *RuntimeError) {
+	switch n.Kind {
+	case aNumberLiteral:
+		i, err := strconv.Atoi(n.Value)
+		if err != nil {
+			return Value{}, typeError(n.token, "invalid int %s", n.Value)
+		}
+		return IntValue(i), nil
+	case aFloatLiteral:
+		f, err := strconv.ParseFloat(n.Value, 64)
+		if err != nil {
+			return Value{}, typeError(n.token, "invalid float %s", n.Value)
+		}
+		return FloatValue(f), nil
+	case aStringLiteral:
+		return StringValue(n.Value), nil
+	case aBoolLiteral:
+		return BoolValue(n.Value == "true"), nil
+	}
+	return Value{}, nil
+}
+
+// unaryOperation applies the prefix operator of the token t
+func unaryOperation(t Token, v Value) (Value, *RuntimeError) {
+	switch t.kind {
+	case tNot:
+		return BoolValue(!v.truthy()), nil
+	case tMinus, tPlus:
+		if !v.isNumber() {
+			return Value{}, typeError(t, "can not apply %s to %s", t.value, v.Type())
+		}
+		if t.kind == tPlus {
+			return v, nil
+		}
+		if v.kind == vFloat {
+			return FloatValue(-v.flt), nil
+		}
+		return IntValue(-v.num), nil
+	}
+	return Value{}, runtimeError(t, codeInternal, "unknown operator %s", t.value)
+}
+
+// binaryOperation applies the binary operator of the token t
+func binaryOperation(t Token, left Value, right Value) (Value, *RuntimeError) {
+	switch t.kind {
+	case tAnd:
+		return BoolValue(left.truthy() && right.truthy()), nil
+	case tOr:
+		return BoolValue(left.truthy() || right.truthy()), nil
+	}
+	if left.isNumber() && right.isNumber() && (left.kind == vFloat || right.kind == vFloat) {
+		return floatOperation(t, left.float(), right.float())
+	}
+	switch t.kind {
+	case tCalcEqual:
+		return BoolValue(equal(left, right)), nil
+	case tCalcNotEqual:
+		return BoolValue(!equal(left, right)), nil
+	}
+
+	if left.kind == vString && right.kind == vString {
+		return stringOperation(t, left.str, right.str)
+	}
+	if left.kind != vInt || right.kind != vInt {
+		return Value{}, typeError(t, "can not apply %s to %s and %s", t.value, left.Type(), right.Type())
+	}
+	switch t.kind {
+	case tPlus:
+		return IntValue(left.num + right.num), nil
+	case tMinus:
+		return IntValue(left.num - right.num), nil
+	case tMultiply:
+		return IntValue(left.num * right.num), nil
+	case tDivide:
+		if right.num == 0 {
+			return Value{}, runtimeError(t, codeDivisionByZero, "division by zero")
+		}
+		return IntValue(left.num / right.num), nil
+	case tModulo:
+		if right.num == 0 {
+			return Value{}, runtimeError(t, codeDivisionByZero, "division by zero")
+		}
+		return IntValue(left.num % right.num), nil
+	case tCalcLessThan:
+		return BoolValue(left.num < right.num), nil
+	case tCalcLessEqual:
+		return BoolValue(left.num <= right.num), nil
+	case tCalcGreaterThan:
+		return BoolValue(left.num > right.num), nil
+	case tCalcGreaterEqual:
+		return BoolValue(left.num >= right.num), nil
+	}
+	return Value{}, runtimeError(t, codeInternal, "unknown operator %s", t.value)
+}
+
+// equalsToken is the `==` the value of a case at t is compared with the
+// subject of the switch by
+func equalsToken(t Token) Token {
+	return Token{kind: tCalcEqual, value: "==", line: t.line, col: t.col}
+}
+
+// isNumber tells whether the value is an int or a float
+func (v Value) isNumber() bool {
+	return v.kind == vInt || v.kind == vFloat
+}
+
+// float promotes a number to a float
+func (v Value) float() float64 {
+	if v.kind == vInt {
+		return float64(v.num)
+	}
+	return v.flt
+}
+
+// floatOperation applies the binary operator of the token t to two numbers,
+// one of them at least was a float
+func floatOperation(t Token, left float64, right float64) (Value, *RuntimeError) {
+	switch t.kind {
+	case tCalcEqual:
+		return BoolValue(left == right), nil
+	case tCalcNotEqual:
+		return BoolValue(left != right), nil
+	case tPlus:
+		return FloatValue(left + right), nil
+	case tMinus:
+		return FloatValue(left - right), nil
+	case tMultiply:
+		return FloatValue(left * right), nil
+	case tDivide:
+		if right == 0 {
+			return Value{}, runtimeError(t, codeDivisionByZero, "division by zero")
+		}
+		return FloatValue(left / right), nil
+	case tModulo:
+		if right == 0 {
+			return Value{}, runtimeError(t, codeDivisionByZero, "division by zero")
+		}
+		return FloatValue(math.Mod(left, right)), nil
+	case tCalcLessThan:
+		return BoolValue(left < right), nil
+	case tCalcLessEqual:
+		return BoolValue(left <= right), nil
+	case tCalcGreaterThan:
+		return BoolValue(left > right), nil
+	case tCalcGreaterEqual:
+		return BoolValue(left >= right), nil
+	}
+	return Value{}, runtimeError(t, codeInternal, "unknown operator %s", t.value)
+}
+
+// stringOperation applies the binary operator of the token t to two strings
+func stringOperation(t Token, left string, right string) (Value, *RuntimeError) {
+	switch t.kind {
+	case tPlus:
+		return StringValue(left + right), nil
+	case tCalcLessThan:
+		return BoolValue(left < right), nil
+	case tCalcLessEqual:
+		return BoolValue(left <= right), nil
+	case tCalcGreaterThan:
+		return BoolValue(left > right), nil
+	case tCalcGreaterEqual:
+		return BoolValue(left >= right), nil
+	}
+	return Value{}, typeError(t, "can not apply %s to string and string", t.value)
+}
+
+// equal compares two values for == and !=, the numbers are already promoted
+func equal(left Value, right Value) bool {
+	return equalSeen(left, right, nil)
+}
+
+// equalSeen is equal, seen are the pairs of lists and maps being compared
+// further up. A pair that comes back is taken as equal, so lists holding
+// themselves compare like `[...]` prints them instead of going on forever.
+func equalSeen(left Value, right Value, seen [][2]interface{}) bool {
+	switch {
+	case left.kind == vList && right.kind == vList:
+		if left.list == right.list {
+			return true
+		}
+		if len(left.list.items) != len(right.list.items) {
+			return false
+		}
+		pair := [2]interface{}{left.list, right.list}
+		for _, s := range seen {
+			if s == pair {
+				return true
+			}
+		}
+		seen = append(seen, pair)
+		for i, item := range left.list.items {
+			if !equalItems(item, right.list.items[i], seen) {
+				return false
+			}
+		}
+		return true
+	case left.kind == vMap && right.kind == vMap:
+		if left.dict == right.dict {
+			return true
+		}
+		if len(left.dict.keys) != len(right.dict.keys) {
+			return false
+		}
+		pair := [2]interface{}{left.dict, right.dict}
+		for _, s := range seen {
+			if s == pair {
+				return true
+			}
+		}
+		seen = append(seen, pair)
+		for k, v := range left.dict.values {
+			other, ok := right.dict.values[k]
+			if !ok || !equalItems(v, other, seen) {
+				return false
+			}
+		}
+		return true
+	}
+	return left == right
+}
+
+// equalItems compares two items of lists or maps, with the numbers promoted
+func equalItems(left Value, right Value, seen [][2]interface{}) bool {
+	if left.isNumber() && right.isNumber() && (left.kind == vFloat || right.kind == vFloat) {
+		return left.float() == right.float()
+	}
+	return equalSeen(left, right, seen)
+}
+
+// mapKey checks that the value can be a key of a map and gives back the key,
+// a float that is a whole number is the int
+func mapKey(t Token, key Value) (Value, *RuntimeError) {
+	switch key.kind {
+	case vInt, vString, vBool:
+		return key, nil
+	case vFloat:
+		if math.IsNaN(key.flt) {
+			return Value{}, typeError(t, "NaN can not be a key of a map")
+		}
+		if key.flt == math.Trunc(key.flt) && math.Abs(key.flt) < 1<<53 {
+			return IntValue(int(key.flt)), nil
+		}
+		return key, nil
+	}
+	return Value{}, typeError(t, "a %s can not be a key of a map", key.Type())
+}
+
+// indexValue gives back the item index of the list or the value of the key
+// index of the map, t is the index for the errors
+func indexValue(t Token, target Value, index Value) (Value, *RuntimeError) {
+	if target.kind == vMap {
+		key, err := mapKey(t, index)
+		if err != nil {
+			return Value{}, err
+		}
+		v, ok := target.dict.values[key]
+		if !ok {
+			return Value{}, runtimeError(t, codeKeyNotFound, "key %s is not in the map", key.formatItem(nil))
+		}
+		return v, nil
+	}
+	i, err := checkIndex(t, target, index)
+	if err != nil {
+		return Value{}, err
+	}
+	return target.list.items[i], nil
+}
+
+// mapLiteral builds the map of a literal, entries are the keys and the values
+// one after the other and t is the `{` for the errors
+func mapLiteral(t Token, entries []Value) (Value, *RuntimeError) {
+	d := newDict()
+	for i := 0; i+1 < len(entries); i += 2 {
+		key, err := mapKey(t, entries[i])
+		if err != nil {
+			return Value{}, err
+		}
+		d.set(key, entries[i+1])
+	}
+	return Value{kind: vMap, dict: d}, nil
+}
+
+// setIndex assigns the item index of the list or the key index of the map
+func setIndex(t Token, target Value, index Value, v Value) *RuntimeError {
+	if target.kind == vMap {
+		key, err := mapKey(t, index)
+		if err != nil {
+			return err
+		}
+		target.dict.set(key, v)
+		return nil
+	}
+	i, err := checkIndex(t, target, index)
+	if err != nil {
+		return err
+	}
+	target.list.items[i] = v
+	return nil
+}
+
+// checkIndex makes sure the index is in the list
+func checkIndex(t Token, target Value, index Value) (int, *RuntimeError) {
+	if target.kind != vList {
+		return 0, typeError(t, "can not index %s", target.Type())
+	}
+	if index.kind != vInt {
+		return 0, typeError(t, "the index of a list is an int, not %s", index.Type())
+	}
+	if index.num < 0 || index.num >= len(target.list.items) {
+		return 0, runtimeError(t, codeIndexOutOfRange, "index %d out of range of a list of %d items", index.num, len(target.list.items))
+	}
+	return index.num, nil
+}
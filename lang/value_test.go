@@ -0,0 +1,28 @@
+package lang
+
+import "testing"
+
+func TestValueFloat(t *testing.T) {
+	tests := []struct {
+		v    Value
+		want string
+	}{
+		{FloatValue(3), "3.0"},
+		{FloatValue(0.1), "0.1"},
+		{FloatValue(-2.5), "-2.5"},
+		{FloatValue(1e21), "1e+21"},
+		{FloatValue(0.00001), "1e-05"},
+		{FloatValue(123456789), "123456789.0"},
+	}
+	for _, tt := range tests {
+		if got := tt.v.String(); got != tt.want {
+			t.Errorf("%v.String() = %q, want %q", tt.v.flt, got, tt.want)
+		}
+	}
+	if f, ok := FloatValue(1.5).Float(); !ok || f != 1.5 {
+		t.Errorf("Float() = %v, %v", f, ok)
+	}
+	if _, ok := IntValue(1).Float(); ok {
+		t.Errorf("an int is not a float")
+	}
+}
@@ -0,0 +1,280 @@
+/**
+ * ============================================================================
+ *                                  ┌( ಠ_ಠ)┘
+ *                                   THE VM
+ * ============================================================================
+ */
+
+package lang
+
+import (
+	"fmt"
+	"io"
+)
+
+// vmFrame is one call of a chunk, its locals live on the stack from base
+type vmFrame struct {
+	chunk *chunk
+	ip    int
+	base  int
+	// call is where the chunk was called from
+	call Token
+	// set tells an assigned local from an unset one, an unset local reads the
+	// global of the same name like the tree backend does
+	set []bool
+}
+
+// VM runs the bytecode of a program
+type VM struct {
+	program *Program
+	globals []Value
+	// defined tells the assigned globals from the undefined ones
+	defined   []bool
+	functions []*chunk
+	// host map[name]function registered by the Go program
+	host map[string]HostFunc
+	// hostGlobals map[name]value set by the Go program
+	hostGlobals map[string]Value
+	stack       []Value
+	frames      []vmFrame
+	// out is where print writes to
+	out io.Writer
+}
+
+// NewVM creates a VM printing to out
+func NewVM(out io.Writer) *VM {
+	return &VM{out: out, host: make(map[string]HostFunc), hostGlobals: make(map[string]Value)}
+}
+
+// Reset forgets the program that ran and the globals set by the host, so the
+// next run starts from scratch. The host functions stay.
+func (m *VM) Reset() {
+	m.program = nil
+	m.globals, m.defined, m.functions = nil, nil, nil
+	m.hostGlobals = make(map[string]Value)
+	m.stack = m.stack[:0]
+	m.frames = m.frames[:0]
+}
+
+func (m *VM) push(v Value) {
+	m.stack = append(m.stack, v)
+}
+
+func (m *VM) pop() Value {
+	v := m.stack[len(m.stack)-1]
+	m.stack = m.stack[:len(m.stack)-1]
+	return v
+}
+
+// fail fills the trace of the calls into err
+func (m *VM) fail(err *RuntimeError) error {
+	if err.trace == nil {
+		for i := len(m.frames) - 1; i > 0; i-- {
+			err.trace = append(err.trace, traceEntry{m.frames[i].chunk.name, m.frames[i].call})
+		}
+	}
+	return err
+}
+
+// Run the program from its first instruction until it returns or fails. It
+// starts with the globals of SetGlobal only, the ones an earlier run
+// assigned are gone, unlike the ones of Interpreter.Run.
+func (m *VM) Run(p *Program) error {
+	m.program = p
+	m.globals = make([]Value, len(p.globals))
+	m.defined = make([]bool, len(p.globals))
+	for slot, name := range p.globals {
+		m.globals[slot], m.defined[slot] = m.hostGlobals[name]
+	}
+	m.functions = make([]*chunk, len(p.functions))
+	m.stack = m.stack[:0]
+	m.frames = append(m.frames[:0], vmFrame{chunk: p.chunks[0]})
+
+	frame := &m.frames[0]
+	code := frame.chunk.code
+	for {
+		op := code[frame.ip]
+		frame.ip++
+		switch op {
+		case opConst:
+			m.push(p.constants[code[frame.ip]])
+			frame.ip++
+
+		case opPop:
+			m.stack = m.stack[:len(m.stack)-1]
+
+		case opDup:
+			m.push(m.stack[len(m.stack)-1])
+
+		case opLoadGlobal:
+			slot := code[frame.ip]
+			if !m.defined[slot] {
+				return m.fail(runtimeError(frame.chunk.tokens[code[frame.ip+1]], codeUndefinedVariable, "undefined variable %s", p.globals[slot]))
+			}
+			m.push(m.globals[slot])
+			frame.ip += 2
+
+		case opStoreGlobal:
+			slot := code[frame.ip]
+			m.globals[slot] = m.pop()
+			m.defined[slot] = true
+			frame.ip++
+
+		case opLoadLocal:
+			slot, global := code[frame.ip], code[frame.ip+1]
+			switch {
+			case frame.set[slot]:
+				m.push(m.stack[frame.base+slot])
+			case m.defined[global]:
+				m.push(m.globals[global])
+			default:
+				return m.fail(runtimeError(frame.chunk.tokens[code[frame.ip+2]], codeUndefinedVariable, "undefined variable %s", p.globals[global]))
+			}
+			frame.ip += 3
+
+		case opStoreLocal:
+			slot := code[frame.ip]
+			m.stack[frame.base+slot] = m.pop()
+			frame.set[slot] = true
+			frame.ip++
+
+		case opBinary:
+			right := m.pop()
+			left := m.pop()
+			v, err := binaryOperation(frame.chunk.tokens[code[frame.ip]], left, right)
+			if err != nil {
+				return m.fail(err)
+			}
+			m.push(v)
+			frame.ip++
+
+		case opUnary:
+			v, err := unaryOperation(frame.chunk.tokens[code[frame.ip]], m.pop())
+			if err != nil {
+				return m.fail(err)
+			}
+			m.push(v)
+			frame.ip++
+
+		case opJump:
+			frame.ip = code[frame.ip]
+
+		case opJumpIfFalse:
+			if m.pop().truthy() {
+				frame.ip++
+			} else {
+				frame.ip = code[frame.ip]
+			}
+
+		case opJumpIfTrue:
+			if m.pop().truthy() {
+				frame.ip = code[frame.ip]
+			} else {
+				frame.ip++
+			}
+
+		case opDefine:
+			m.functions[code[frame.ip+1]] = p.chunks[code[frame.ip]]
+			frame.ip += 2
+
+		case opCall:
+			slot, params, t := code[frame.ip], code[frame.ip+1], frame.chunk.tokens[code[frame.ip+2]]
+			frame.ip += 3
+			function := m.functions[slot]
+			if f, ok := m.host[p.functions[slot]]; function == nil && ok {
+				// the params are on top of the stack, the result takes their place
+				base := len(m.stack) - params
+				v, err := callHost(f, t, append([]Value(nil), m.stack[base:]...))
+				if err != nil {
+					return m.fail(err)
+				}
+				m.stack = append(m.stack[:base], v)
+				continue
+			}
+			if function == nil {
+				return m.fail(runtimeError(t, codeUndefinedFunction, "undefined function %s", p.functions[slot]))
+			}
+			if params != function.params {
+				return m.fail(runtimeError(t, codeWrongArity, "%s wants %d params but got %d", function.name, function.params, params))
+			}
+			if len(m.frames) > maxFrames {
+				return m.fail(runtimeError(t, codeStackOverflow, "stack overflow calling %s", function.name))
+			}
+			set := make([]bool, function.locals)
+			for i := 0; i < params; i++ {
+				set[i] = true
+			}
+			for i := params; i < function.locals; i++ {
+				m.push(Value{})
+			}
+			m.frames = append(m.frames, vmFrame{chunk: function, base: len(m.stack) - function.locals, call: t, set: set})
+			frame = &m.frames[len(m.frames)-1]
+			code = function.code
+
+		case opBuiltin:
+			b, params, t := builtins[code[frame.ip]], code[frame.ip+1], frame.chunk.tokens[code[frame.ip+2]]
+			frame.ip += 3
+			if err := b.checkParams(t, params); err != nil {
+				return m.fail(err)
+			}
+			// the params are on top of the stack, the result takes their place
+			base := len(m.stack) - params
+			v, err := b.call(m.out, t, m.stack[base:])
+			if err != nil {
+				return m.fail(err)
+			}
+			m.stack = append(m.stack[:base], v)
+
+		case opList:
+			// the items are on top of the stack, the list takes their place
+			base := len(m.stack) - code[frame.ip]
+			v := ListValue(m.stack[base:]...)
+			m.stack = append(m.stack[:base], v)
+			frame.ip++
+
+		case opMap:
+			base := len(m.stack) - code[frame.ip]
+			v, err := mapLiteral(frame.chunk.tokens[code[frame.ip+1]], m.stack[base:])
+			if err != nil {
+				return m.fail(err)
+			}
+			m.stack = append(m.stack[:base], v)
+			frame.ip += 2
+
+		case opIndex:
+			index := m.pop()
+			v, err := indexValue(frame.chunk.tokens[code[frame.ip]], m.pop(), index)
+			if err != nil {
+				return m.fail(err)
+			}
+			m.push(v)
+			frame.ip++
+
+		case opDup2:
+			m.push(m.stack[len(m.stack)-2])
+			m.push(m.stack[len(m.stack)-2])
+
+		case opSetIndex:
+			v, index := m.pop(), m.pop()
+			if err := setIndex(frame.chunk.tokens[code[frame.ip]], m.pop(), index, v); err != nil {
+				return m.fail(err)
+			}
+			m.push(v)
+			frame.ip++
+
+		case opReturn:
+			if len(m.frames) == 1 {
+				return nil
+			}
+			result := m.pop()
+			m.stack = m.stack[:frame.base]
+			m.push(result)
+			m.frames = m.frames[:len(m.frames)-1]
+			frame = &m.frames[len(m.frames)-1]
+			code = frame.chunk.code
+
+		default:
+			return fmt.Errorf("unknown opcode %d in %s", op, frame.chunk.name)
+		}
+	}
+}
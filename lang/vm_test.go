@@ -0,0 +1,184 @@
+package lang
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// runBackend runs a program on one of the backends, and gives back what it
+// printed and the error it stopped on
+func runBackend(t *testing.T, ast Node, backend string) (string, string) {
+	t.Helper()
+	var out bytes.Buffer
+	err := Protect("runtime", func() error {
+		if backend == "vm" {
+			program, err := Compile(&ast)
+			if err != nil {
+				return err
+			}
+			return NewVM(&out).Run(program)
+		}
+		_, err := NewInterpreter(&out).run(&ast)
+		return err
+	})
+	if err != nil {
+		return out.String(), err.Error()
+	}
+	return out.String(), ""
+}
+
+func sampleFiles(t *testing.T) []string {
+	t.Helper()
+	files, err := filepath.Glob(filepath.Join("testdata", "*.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return append(files, filepath.Join("..", "test.txt"))
+}
+
+// TestBackendsConformance runs every sample program on both backends, they
+// have to print the same things
+func TestBackendsConformance(t *testing.T) {
+	for _, file := range sampleFiles(t) {
+		t.Run(file, func(t *testing.T) {
+			content, err := os.ReadFile(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			tokens, err := Tokenize(content)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ast, err := Parse(tokens)
+			if err != nil {
+				t.Fatal(err)
+			}
+			treeOut, treeErr := runBackend(t, ast, "tree")
+			vmOut, vmErr := runBackend(t, ast, "vm")
+			if treeOut == "" {
+				t.Errorf("the sample printed nothing")
+			}
+			if treeErr != "" {
+				t.Errorf("the sample stopped on %q", treeErr)
+			}
+			if treeOut != vmOut {
+				t.Errorf("tree printed\n%s\nvm printed\n%s", treeOut, vmOut)
+			}
+			if treeErr != vmErr {
+				t.Errorf("tree stopped on %q, vm stopped on %q", treeErr, vmErr)
+			}
+		})
+	}
+}
+
+// TestBackendsRuntimeErrors makes sure both backends stop on the same errors
+func TestBackendsRuntimeErrors(t *testing.T) {
+	tests := []string{
+		"a = 1 / 0",
+		"f(1)",
+		"int f(int a) { return a }\nf()",
+		"int f(int a) { return f(a) }\nprint(f(1))",
+		"print(1)\nint f() { return g() }\nprint(f())",
+		"a = \"x\" + 1",
+		"print(1 < true)",
+		"print(nil * 2)",
+		"print(a)",
+		"int f() { return a }\nprint(f())",
+		"len()",
+		"len(\"a\", \"b\")",
+		"len(1)",
+		"min()",
+		"max(1, \"2\")",
+		"abs(nil)",
+		"int(\"x1\")",
+		"int(nil)",
+		"print(1.5 / 0)",
+		"print(1.5 + \"a\")",
+		"float(\"1.5x\")",
+		"print(\"a\" - \"b\")",
+		"print(\"a\" < 1)",
+		"print(\"n: \" + 1)",
+		"print(-\"a\")",
+		"print(+nil)",
+		"print(1 % 0)",
+		"print(1.5 % 0)",
+		"x += 1",
+		"s = \"a\"\ns -= 1",
+		"int f() { n++ }\nf()",
+		"a = [1, 2]\nprint(a[2])",
+		"a = [1, 2]\na[-1] = 0",
+		"a = [1]\nprint(a[\"0\"])",
+		"a = 1\nprint(a[0])",
+		"pop([])",
+		"push(1, 2)",
+		"a = []\npush(a)",
+		"print([1] + [2])",
+		"print([1] < [2])",
+		"m = {\"a\": 1}\nprint(m[\"b\"])",
+		"m = {[1]: 1}",
+		"m = {}\nm[{}] = 1",
+		"print(has([], 1))",
+		"print(keys([]))",
+		"delete({}, nil)",
+		"print({} + {})",
+		"a = [1]\na[5] += 1",
+		"a = [1]\na[0] -= \"x\"",
+		"m = {}\nm[\"k\"]++",
+		"switch (1) {\ncase 2, 1 / 0:\n}",
+		"switch (x) {\n}",
+		"for (i = 0; i < 3; i++) {\n\tswitch (i) {\n\tcase 1:\n\t\tbreak\n\t}\n\tprint(i)\n}\nprint(1 / 0)",
+		"for (i = 0; i < 3; i++) {\n\tswitch (i) {\n\tcase 2:\n\t\tprint(len(i))\n\tdefault:\n\t\tcontinue\n\t}\n}",
+		"int(1e300 * 1e300)",
+		"int(1e300)",
+		"int(-1e19)",
+		"int(9223372036854775807.0)",
+		"int(0.0 / 0.0)",
+		"int f() { return len(1) }\nf()",
+		"int f(int n) { return 1 / n }\nint g() { return f(0) }\ng()",
+	}
+	for _, source := range tests {
+		ast, err := parseString(t, source)
+		if err != nil {
+			t.Fatalf("parse(%q): %v", source, err)
+		}
+		treeOut, treeErr := runBackend(t, ast, "tree")
+		vmOut, vmErr := runBackend(t, ast, "vm")
+		if treeErr == "" {
+			t.Errorf("run(%q) did not fail", source)
+		}
+		if treeOut != vmOut || treeErr != vmErr {
+			t.Errorf("run(%q): tree gave %q, %q but vm gave %q, %q", source, treeOut, treeErr, vmOut, vmErr)
+		}
+	}
+}
+
+func benchmarkBackend(b *testing.B, backend string) {
+	content, err := os.ReadFile(filepath.Join("testdata", "loops.txt"))
+	if err != nil {
+		b.Fatal(err)
+	}
+	tokens, err := Tokenize(content)
+	if err != nil {
+		b.Fatal(err)
+	}
+	ast, err := Parse(tokens)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var out bytes.Buffer
+		if backend == "vm" {
+			program, _ := Compile(&ast)
+			_ = NewVM(&out).Run(program)
+		} else {
+			_, _ = NewInterpreter(&out).run(&ast)
+		}
+	}
+}
+
+func BenchmarkTree(b *testing.B) { benchmarkBackend(b, "tree") }
+
+func BenchmarkVM(b *testing.B) { benchmarkBackend(b, "vm") }
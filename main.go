@@ -2,64 +2,281 @@ package main
 
 import (
 	"encoding/json"
+	"flag"
 	"fmt"
+	"io"
 	"os"
+	"path/filepath"
+	"strings"
+
+	"goCompiler/lang"
 )
 
+const usage = `usage: goCompiler <command> [flags] [file ...]
+
+commands:
+  lex     print the tokens of the files
+  parse   print the ast of the files as json
+  run     run the files
+  check   report lexer and parser errors, print nothing else
+  fmt     print the formatted source of the files
+  repl    read, run and print statements interactively
+
+With no file, or when the file is "-", the source is read from stdin.
+
+flags:
+  -o file
+        write the output of the command to file instead of stdout
+  --emit=tokens,ast,json
+        also write file.token, file.ast and file.ast.json next to each input
+  --backend=tree|vm
+        run the ast directly, or compile it to bytecode for the vm (default tree)
+  --color=auto|always|never
+        color the errors, auto colors them when stderr is a terminal (default auto)
+  --diagnostics-format=text|json|sarif
+        write the errors of all files to stderr for people, as a json array or
+        as a sarif log (default text)
+`
+
+// commands that work on files, the repl is started on its own
+var commands = map[string]bool{
+	"lex":   true,
+	"parse": true,
+	"run":   true,
+	"check": true,
+	"fmt":   true,
+}
+
+// artifacts that can be asked for with --emit
+var emitKinds = map[string]string{
+	"tokens": ".token",
+	"ast":    ".ast",
+	"json":   ".ast.json",
+}
+
+type options struct {
+	command string
+	output  io.Writer
+	emit    map[string]bool
+	backend string
+	// color the errors
+	color bool
+	// text, json or sarif
+	diagnosticsFormat string
+}
+
 func main() {
-	content, err := os.ReadFile("./test.txt")
-	if err != nil {
-		fmt.Println(err)
-		return
+	os.Exit(cli(os.Args[1:]))
+}
+
+// cli runs a command and returns the exit status
+func cli(args []string) int {
+	if len(args) == 0 || args[0] == "-h" || args[0] == "--help" || args[0] == "help" {
+		fmt.Fprint(os.Stderr, usage)
+		if len(args) == 0 {
+			return 2
+		}
+		return 0
+	}
+	if args[0] == "repl" {
+		return lang.REPL(os.Stdin, os.Stdout)
+	}
+	opts := options{command: args[0], output: os.Stdout, emit: map[string]bool{}}
+	if !commands[opts.command] {
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n%s", opts.command, usage)
+		return 2
+	}
+
+	flags := flag.NewFlagSet(opts.command, flag.ContinueOnError)
+	flags.SetOutput(os.Stderr)
+	flags.Usage = func() { fmt.Fprint(os.Stderr, usage) }
+	outputPath := flags.String("o", "", "write the output of the command to file")
+	emit := flags.String("emit", "", "comma separated artifacts to write: tokens,ast,json")
+	flags.StringVar(&opts.backend, "backend", "tree", "tree or vm")
+	color := flags.String("color", "auto", "auto, always or never")
+	flags.StringVar(&opts.diagnosticsFormat, "diagnostics-format", "text", "text, json or sarif")
+	if err := flags.Parse(args[1:]); err != nil {
+		return 2
 	}
-	// 词法分析
-	tokens, err := tokenize(content)
+	switch *color {
+	case "auto":
+		opts.color = isTerminal(os.Stderr)
+	case "always":
+		opts.color = true
+	case "never":
+	default:
+		fmt.Fprintf(os.Stderr, "unknown color %q, should be auto, always or never\n", *color)
+		return 2
+	}
+	switch opts.diagnosticsFormat {
+	case "text", "json", "sarif":
+	default:
+		fmt.Fprintf(os.Stderr, "unknown diagnostics format %q, should be text, json or sarif\n", opts.diagnosticsFormat)
+		return 2
+	}
+	if opts.backend != "tree" && opts.backend != "vm" {
+		fmt.Fprintf(os.Stderr, "unknown backend %q, should be tree or vm\n", opts.backend)
+		return 2
+	}
+	if *emit != "" {
+		for _, kind := range strings.Split(*emit, ",") {
+			kind = strings.TrimSpace(kind)
+			if _, ok := emitKinds[kind]; !ok {
+				fmt.Fprintf(os.Stderr, "unknown artifact %q for --emit\n", kind)
+				return 2
+			}
+			opts.emit[kind] = true
+		}
+	}
+	if *outputPath != "" {
+		f, err := os.Create(*outputPath)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		defer f.Close()
+		opts.output = f
+	}
+
+	files := flags.Args()
+	if len(files) == 0 {
+		files = []string{"-"}
+	}
+	status := 0
+	var diagnostics []lang.Diagnostic
+	for _, file := range files {
+		content, err := readSource(file)
+		if err == nil {
+			err = process(&opts, file, content)
+		}
+		if err != nil {
+			fileDiagnostics := lang.Diagnostics(displayName(file), err)
+			if opts.diagnosticsFormat == "text" {
+				lang.RenderDiagnostics(os.Stderr, content, fileDiagnostics, opts.color)
+			}
+			diagnostics = append(diagnostics, fileDiagnostics...)
+			status = 1
+		}
+	}
+	// the tools want one document with the errors of all files
+	switch opts.diagnosticsFormat {
+	case "json":
+		lang.WriteDiagnosticsJSON(os.Stderr, diagnostics)
+	case "sarif":
+		lang.WriteDiagnosticsSARIF(os.Stderr, "goCompiler", diagnostics)
+	}
+	return status
+}
+
+// readSource reads a file, or stdin for "-"
+func readSource(file string) ([]byte, error) {
+	if file == "-" {
+		return io.ReadAll(os.Stdin)
+	}
+	return os.ReadFile(file)
+}
+
+// process the content of one file through the stages of the command
+func process(opts *options, file string, content []byte) error {
+	// 词法分析，its errors are reported with the ones of the parser
+	var tokens []lang.Token
+	var lexErr error
+	err := lang.Protect("lexer", func() error {
+		tokens, lexErr = lang.TokenizeTrivia(content)
+		return nil
+	})
 	if err != nil {
-		fmt.Println(err.Error())
-		return
+		return err
+	}
+	if err = emitArtifact(opts, file, "tokens", []byte(fmt.Sprintf("%+v", tokens))); err != nil {
+		return err
 	}
-	//fmt.Printf("%+v\n", tokens)
-	_ = os.WriteFile("test.token", []byte(fmt.Sprintf("%+v", tokens)), 0o644)
+	switch opts.command {
+	case "lex":
+		for _, t := range tokens {
+			fmt.Fprintln(opts.output, t)
+		}
+		return lexErr
+	case "fmt":
+		// the characters that are not valid are not in the tokens
+		if lexErr != nil {
+			return lexErr
+		}
+		_, err = opts.output.Write(lang.Format(tokens))
+		return err
+	}
+
 	// 语法分析
-	ast, err := parser(&tokens)
+	var ast lang.Node
+	err = lang.Protect("parser", func() (err error) {
+		ast, err = lang.Parse(tokens)
+		return lang.JoinErrors(lexErr, err)
+	})
 	if err != nil {
-		fmt.Println(err.Error())
-		return
+		return err
+	}
+	if err = emitArtifact(opts, file, "ast", []byte(fmt.Sprintf("%+v", ast))); err != nil {
+		return err
 	}
-	//fmt.Printf("%+v\n", ast)
-	_ = os.WriteFile("test.ast", []byte(fmt.Sprintf("%+v", ast)), 0o644)
-	j, e := json.Marshal(ast)
-	if e == nil {
-		_ = os.WriteFile("test.ast.json", j, 0o644)
+	if opts.emit["json"] {
+		j, err := json.Marshal(ast)
+		if err != nil {
+			return err
+		}
+		if err = emitArtifact(opts, file, "json", j); err != nil {
+			return err
+		}
+	}
+	switch opts.command {
+	case "parse":
+		j, err := json.MarshalIndent(ast, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(opts.output, "%s\n", j)
+		return err
+	case "check":
+		return nil
 	}
 
 	// 中间代码执行
-	_ = ast.run()
-
-	//flag.Parse()
-	//target := flag.Args()
-	//fmt.Println(target)
-	//for i := 0; i < len(target); i++ {
-	//	content, err := os.ReadFile(target[i])
-	//	if err != nil {
-	//		fmt.Println(err.Error())
-	//		continue
-	//	}
-	//	// 词法分析
-	//	// fmt.Printf("%+v", tokenize(content))
-	//	tokens, err := tokenize(content)
-	//	if err != nil {
-	//		fmt.Println(err.Error())
-	//		continue
-	//	}
-	//	_ = os.WriteFile(target[i]+".token", []byte(fmt.Sprintf("%+v", tokens)), 0o644)
-	//	// 语法分析
-	//	ast, err := parser(tokens)
-	//	if err != nil {
-	//		fmt.Println(err.Error())
-	//		continue
-	//	}
-	//	_ = os.WriteFile(target[i]+".ast", []byte(fmt.Sprintf("%+v", ast)), 0o644)
-	//}
-	return
+	if opts.backend == "vm" {
+		program, err := lang.Compile(&ast)
+		if err != nil {
+			return err
+		}
+		return lang.Protect("runtime", func() error {
+			return lang.NewVM(opts.output).Run(program)
+		})
+	}
+	return lang.Protect("runtime", func() error {
+		_, err := lang.NewInterpreter(opts.output).Run(&ast)
+		return err
+	})
+}
+
+// emitArtifact writes file.token, file.ast or file.ast.json if it was asked for
+func emitArtifact(opts *options, file string, kind string, content []byte) error {
+	if !opts.emit[kind] {
+		return nil
+	}
+	base := file
+	if file == "-" {
+		base = "stdin"
+	}
+	return os.WriteFile(base+emitKinds[kind], content, 0o644)
+}
+
+// isTerminal tells whether f is a terminal rather than a file or a pipe
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// displayName is the name of a file used in messages
+func displayName(file string) string {
+	if file == "-" {
+		return "<stdin>"
+	}
+	return filepath.Clean(file)
 }
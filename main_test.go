@@ -0,0 +1,159 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// runCLI runs the command line and gives back its exit status and what it
+// wrote to stderr
+func runCLI(t *testing.T, args ...string) (int, string) {
+	t.Helper()
+	stderr, err := os.CreateTemp(t.TempDir(), "stderr")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stderr.Close()
+	saved := os.Stderr
+	os.Stderr = stderr
+	status := cli(args)
+	os.Stderr = saved
+	written, err := os.ReadFile(stderr.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return status, string(written)
+}
+
+// writeFile writes a source file into dir and gives back its path
+func writeFile(t *testing.T, dir string, name string, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestCLIExitStatus(t *testing.T) {
+	dir := t.TempDir()
+	good := writeFile(t, dir, "good.txt", "a = 1 + 2\nprint(a)\n")
+	broken := writeFile(t, dir, "broken.txt", "a = 1 +")
+	failing := writeFile(t, dir, "failing.txt", "print(1 / 0)\n")
+	out := filepath.Join(dir, "out")
+
+	tests := []struct {
+		args   []string
+		status int
+		stderr string
+	}{
+		{nil, 2, "usage:"},
+		{[]string{"help"}, 0, "usage:"},
+		{[]string{"compile", good}, 2, "unknown command"},
+		{[]string{"run", "--backend=jit", good}, 2, "unknown backend"},
+		{[]string{"run", "--color=maybe", good}, 2, "unknown color"},
+		{[]string{"run", "--diagnostics-format=xml", good}, 2, "unknown diagnostics format"},
+		{[]string{"run", "--emit=bytecode", good}, 2, "unknown artifact"},
+		{[]string{"run", "--no-such-flag", good}, 2, ""},
+		{[]string{"run", "-o", out, good}, 0, ""},
+		{[]string{"run", "-o", out, "--backend=vm", good}, 0, ""},
+		{[]string{"check", good}, 0, ""},
+		{[]string{"check", broken}, 1, "broken.txt:1:8: error: unexpected end of tokens"},
+		{[]string{"run", "-o", out, failing}, 1, "division by zero"},
+		{[]string{"run", "-o", out, "--backend=vm", failing}, 1, "division by zero"},
+		{[]string{"lex", "-o", out, filepath.Join(dir, "missing.txt")}, 1, "missing.txt"},
+		{[]string{"check", good, broken}, 1, "broken.txt"},
+	}
+	for _, tt := range tests {
+		status, stderr := runCLI(t, tt.args...)
+		if status != tt.status || !strings.Contains(stderr, tt.stderr) {
+			t.Errorf("cli(%q) = %d with stderr %q, want %d with %q", tt.args, status, stderr, tt.status, tt.stderr)
+		}
+	}
+}
+
+func TestCLIOutput(t *testing.T) {
+	dir := t.TempDir()
+	source := writeFile(t, dir, "source.txt", "a=1+2\nprint(a)\n")
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"run"}, "3\n"},
+		{[]string{"run", "--backend=vm"}, "3\n"},
+		{[]string{"fmt"}, "a = 1 + 2\nprint(a)\n"},
+		{[]string{"lex"}, "1:1\tIdentifier\ta\n"},
+		{[]string{"check"}, ""},
+	}
+	for _, tt := range tests {
+		out := filepath.Join(dir, "out")
+		args := append(append(tt.args, "-o", out), source)
+		if status, stderr := runCLI(t, args...); status != 0 {
+			t.Fatalf("cli(%q) = %d: %s", args, status, stderr)
+		}
+		written, err := os.ReadFile(out)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.HasPrefix(string(written), tt.want) || tt.want == "" && len(written) != 0 {
+			t.Errorf("cli(%q) wrote %q, want %q", args, written, tt.want)
+		}
+	}
+}
+
+func TestCLIEmit(t *testing.T) {
+	dir := t.TempDir()
+	source := writeFile(t, dir, "source.txt", "print(1)\n")
+	if status, stderr := runCLI(t, "run", "--emit=tokens, ast,json", "-o", filepath.Join(dir, "out"), source); status != 0 {
+		t.Fatalf("run --emit gave %d: %s", status, stderr)
+	}
+	for _, suffix := range []string{".token", ".ast", ".ast.json"} {
+		content, err := os.ReadFile(source + suffix)
+		if err != nil {
+			t.Errorf("--emit did not write %s: %v", suffix, err)
+			continue
+		}
+		if len(content) == 0 {
+			t.Errorf("--emit wrote an empty %s", suffix)
+		}
+	}
+	content, _ := os.ReadFile(source + ".ast.json")
+	if !json.Valid(content) {
+		t.Errorf("%s.ast.json is not json: %s", source, content)
+	}
+
+	// a file that does not parse still gets its tokens, but no ast
+	broken := writeFile(t, dir, "broken.txt", "print(\n")
+	if status, _ := runCLI(t, "check", "--emit=tokens,ast", broken); status != 1 {
+		t.Errorf("check of a broken file gave %d, want 1", status)
+	}
+	if _, err := os.Stat(broken + ".token"); err != nil {
+		t.Errorf("--emit did not write the tokens of the broken file: %v", err)
+	}
+	if _, err := os.Stat(broken + ".ast"); err == nil {
+		t.Errorf("--emit wrote the ast of a file that does not parse")
+	}
+}
+
+func TestCLIDiagnostics(t *testing.T) {
+	dir := t.TempDir()
+	// a lexer error on the first line and a parser error on the second
+	broken := writeFile(t, dir, "broken.txt", "a = 1 $\nb = (2\n")
+	for _, format := range []string{"json", "sarif"} {
+		status, stderr := runCLI(t, "check", "--diagnostics-format="+format, broken)
+		if status != 1 {
+			t.Errorf("check --diagnostics-format=%s gave %d, want 1", format, status)
+		}
+		if !json.Valid([]byte(stderr)) {
+			t.Errorf("check --diagnostics-format=%s wrote no json: %s", format, stderr)
+		}
+		for _, code := range []string{"lexer/invalid-character", "parser/unexpected-token"} {
+			if !strings.Contains(stderr, code) {
+				t.Errorf("check --diagnostics-format=%s did not report %s:\n%s", format, code, stderr)
+			}
+		}
+	}
+}